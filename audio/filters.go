@@ -0,0 +1,194 @@
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Presets maps equalizer preset names (as used with !eq) to ffmpeg audio filters
+var Presets = map[string]string{
+	"flat": "",
+	"bass": "equalizer=f=60:t=q:w=1:g=6,equalizer=f=150:t=q:w=1:g=3,equalizer=f=3000:t=q:w=2:g=-1",
+	"vocal": "highpass=f=80,equalizer=f=250:t=q:w=1:g=-2,equalizer=f=2500:t=q:w=1.5:g=4," +
+		"equalizer=f=5000:t=q:w=2:g=2",
+	// Squash the dynamic range so quiet parts stay audible without loud parts waking anyone up
+	"night": "acompressor=threshold=0.125:ratio=6:attack=20:release=250:makeup=2,alimiter=limit=0.9",
+}
+
+// Limits for the tempo and pitch multipliers
+const (
+	MinFactor = 0.5
+	MaxFactor = 2.0
+)
+
+const sampleRate = 48000
+
+// Settings describes the audio processing applied to a stream
+type Settings struct {
+	Equalizer string  // Name of an entry in Presets, "" or "flat" for none
+	Mono      bool    // Downmix both channels together
+	Tempo     float64 // Playback speed multiplier keeping pitch, 0 means unchanged
+	Pitch     float64 // Pitch multiplier keeping tempo, 0 means unchanged
+}
+
+// PresetNames returns the available equalizer presets sorted by name
+func PresetNames() []string {
+	names := make([]string, 0, len(Presets))
+	for name := range Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewSettings parses the equalizer preset and filter list stored in a database.Config
+func NewSettings(equalizer, filters string) Settings {
+	settings := Settings{Equalizer: equalizer}
+	for _, field := range strings.Fields(filters) {
+		name, value, _ := strings.Cut(field, "=")
+		if err := settings.Set(name, value); err != nil {
+			continue // ignore stale or hand edited entries
+		}
+	}
+	return settings
+}
+
+// Set enables the named filter, value is only used by filters taking a multiplier.
+func (s *Settings) Set(name, value string) error {
+	switch name {
+	case "mono":
+		s.Mono = true
+	case "tempo", "pitch":
+		factor, err := ParseFactor(value)
+		if err != nil {
+			return err
+		}
+		if name == "tempo" {
+			s.Tempo = factor
+		} else {
+			s.Pitch = factor
+		}
+	default:
+		return errors.New("unknown filter " + name)
+	}
+	return nil
+}
+
+// Unset disables the named filter
+func (s *Settings) Unset(name string) error {
+	switch name {
+	case "mono":
+		s.Mono = false
+	case "tempo":
+		s.Tempo = 0
+	case "pitch":
+		s.Pitch = 0
+	default:
+		return errors.New("unknown filter " + name)
+	}
+	return nil
+}
+
+// IsSet returns whether the named filter is currently enabled
+func (s Settings) IsSet(name string) bool {
+	switch name {
+	case "mono":
+		return s.Mono
+	case "tempo":
+		return s.tempo() != 1
+	case "pitch":
+		return s.pitch() != 1
+	}
+	return false
+}
+
+// ParseFactor parses a tempo or pitch multiplier such as "1.25" or "125%"
+func ParseFactor(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	percent := strings.HasSuffix(value, "%")
+	factor, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSuffix(value, "%"), "x"), 64)
+	if err != nil {
+		return 0, errors.New("invalid multiplier " + value)
+	}
+	if percent {
+		factor /= 100
+	}
+	if factor < MinFactor || factor > MaxFactor {
+		return 0, fmt.Errorf("multiplier must be between %.1f and %.1f", MinFactor, MaxFactor)
+	}
+	return factor, nil
+}
+
+// Filters returns the enabled filters in the format accepted by NewSettings
+func (s Settings) Filters() string {
+	var fields []string
+	if s.Mono {
+		fields = append(fields, "mono")
+	}
+	if s.tempo() != 1 {
+		fields = append(fields, "tempo="+formatFactor(s.tempo()))
+	}
+	if s.pitch() != 1 {
+		fields = append(fields, "pitch="+formatFactor(s.pitch()))
+	}
+	return strings.Join(fields, " ")
+}
+
+// Chain returns the ffmpeg -af filter graph for the settings, "" if no processing is needed
+func (s Settings) Chain() string {
+	var chain []string
+	if preset := Presets[s.Equalizer]; preset != "" {
+		chain = append(chain, preset)
+	}
+	if s.Mono {
+		chain = append(chain, "aformat=channel_layouts=stereo,pan=stereo|c0=0.5*c0+0.5*c1|c1=0.5*c0+0.5*c1")
+	}
+	if s.pitch() != 1 { // resampling shifts both pitch and tempo, atempo below undoes the tempo change
+		chain = append(chain, fmt.Sprintf("aresample=%d,asetrate=%d,aresample=%d",
+			sampleRate, int(sampleRate*s.pitch()), sampleRate))
+	}
+	if tempo := s.tempo() / s.pitch(); tempo != 1 {
+		chain = append(chain, atempo(tempo))
+	}
+	return strings.Join(chain, ",")
+}
+
+// Speed returns the playback speed multiplier, 1 when the tempo is unchanged
+func (s Settings) Speed() float64 {
+	return s.tempo()
+}
+
+func (s Settings) tempo() float64 {
+	if s.Tempo == 0 {
+		return 1
+	}
+	return s.Tempo
+}
+
+func (s Settings) pitch() float64 {
+	if s.Pitch == 0 {
+		return 1
+	}
+	return s.Pitch
+}
+
+// atempo builds a chain of atempo filters, as a single instance only accepts factors between 0.5 and 2
+func atempo(factor float64) string {
+	var chain []string
+	for factor > MaxFactor {
+		chain = append(chain, "atempo=2")
+		factor /= 2
+	}
+	for factor < MinFactor {
+		chain = append(chain, "atempo=0.5")
+		factor /= 0.5
+	}
+	return strings.Join(append(chain, "atempo="+formatFactor(factor)), ",")
+}
+
+func formatFactor(factor float64) string {
+	return strconv.FormatFloat(factor, 'f', -1, 64)
+}
@@ -0,0 +1,30 @@
+package audio
+
+import "testing"
+
+func TestSettingsChain(t *testing.T) {
+	tests := []struct {
+		equalizer, filters string
+		expected           string
+	}{
+		{"", "", ""},
+		{"flat", "", ""},
+		{"", "tempo=1.5", "atempo=1.5"},
+		{"", "tempo=2 pitch=0.5", "aresample=48000,asetrate=24000,aresample=48000,atempo=2,atempo=2"},
+		{"", "mono bogus tempo=9", "aformat=channel_layouts=stereo,pan=stereo|c0=0.5*c0+0.5*c1|c1=0.5*c0+0.5*c1"},
+	}
+
+	for _, tt := range tests {
+		got := NewSettings(tt.equalizer, tt.filters).Chain()
+		if got != tt.expected {
+			t.Errorf("NewSettings(%q, %q).Chain() = %q, want %q", tt.equalizer, tt.filters, got, tt.expected)
+		}
+	}
+}
+
+func TestSettingsFiltersRoundTrip(t *testing.T) {
+	settings := NewSettings("bass", "pitch=1.1 mono tempo=125%")
+	if got := settings.Filters(); got != "mono tempo=1.25 pitch=1.1" {
+		t.Errorf("Filters() = %q, want %q", got, "mono tempo=1.25 pitch=1.1")
+	}
+}
@@ -0,0 +1,119 @@
+package audio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Most bytes buffered ahead of the reader, after which the command is left to block on its output
+const maxPrefetchBuffer = 32 << 20
+
+// Prefetch runs a command in the background and buffers its output until it is read,
+// so the audio is ready by the time it is needed
+type Prefetch struct {
+	cmd  *exec.Cmd
+	out  io.ReadCloser
+	mu   sync.Mutex
+	cond *sync.Cond
+	buf  bytes.Buffer
+	err  error // io.EOF once the command's output ended
+}
+
+// StartPrefetch starts cmd and begins buffering its stdout
+func StartPrefetch(cmd *exec.Cmd) (*Prefetch, error) {
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	prefetch := &Prefetch{cmd: cmd, out: out}
+	prefetch.cond = sync.NewCond(&prefetch.mu)
+	go prefetch.fill()
+	return prefetch, nil
+}
+
+func (p *Prefetch) fill() {
+	chunk := make([]byte, 32*1024)
+	for {
+		n, err := p.out.Read(chunk)
+
+		p.mu.Lock()
+		p.buf.Write(chunk[:n])
+		if err != nil && p.err == nil {
+			p.err = err
+		}
+		for p.err == nil && p.buf.Len() > maxPrefetchBuffer {
+			p.cond.Wait()
+		}
+		done := p.err != nil
+		p.cond.Broadcast()
+		p.mu.Unlock()
+
+		if done {
+			return
+		}
+	}
+}
+
+// Read returns buffered output, blocking until there is some or the command finished
+func (p *Prefetch) Read(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for p.buf.Len() == 0 && p.err == nil {
+		p.cond.Wait()
+	}
+	if p.buf.Len() == 0 {
+		return 0, p.err
+	}
+	n, _ := p.buf.Read(b)
+	p.cond.Broadcast()
+	return n, nil
+}
+
+// Buffered returns how many bytes are waiting to be read
+func (p *Prefetch) Buffered() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.buf.Len()
+}
+
+// Close kills the command and discards anything buffered
+func (p *Prefetch) Close() error {
+	p.mu.Lock()
+	if p.err == nil || p.err == io.EOF {
+		p.err = errors.New("prefetch closed")
+	}
+	p.buf.Reset()
+	p.cond.Broadcast()
+	p.mu.Unlock()
+
+	if p.cmd.Process != nil {
+		p.cmd.Process.Kill() //#nosec G104 -- process may have already exited
+	}
+	p.cmd.Wait() //#nosec G104 -- killed processes always report an error
+	return nil
+}
+
+// Duration asks ffprobe for the length of a local file
+func Duration(path string) (time.Duration, error) {
+	// #nosec G204 -- path comes from the media database
+	output, err := exec.Command("ffprobe", "-v", "error", "-show_entries", "format=duration", "-of", "csv=p=0",
+		"-i", path).Output()
+	if err != nil {
+		return 0, err
+	}
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(string(output)), 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
@@ -0,0 +1,144 @@
+package audio
+
+import (
+	"errors"
+	"io"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	"layeh.com/gumble/gumble"
+	"layeh.com/gumble/gumbleffmpeg"
+)
+
+// Input describes where the audio of a stream comes from, exactly one field should be set.
+type Input struct {
+	Path   string        // Local file (or anything else ffmpeg can open by itself)
+	Cmd    *exec.Cmd     // Command writing audio to stdout, such as yt-dlp
+	Reader io.ReadCloser // Already opened audio data
+}
+
+// NewStream creates a stream for in with settings applied, starting offset into the media
+func NewStream(client *gumble.Client, in Input, settings Settings, offset time.Duration) *gumbleffmpeg.Stream {
+	chain := settings.Chain()
+	if chain != "" {
+		return gumbleffmpeg.New(client, gumbleffmpeg.SourceReader(&pipeline{in: in, filter: chain, offset: offset}))
+	}
+
+	var source gumbleffmpeg.Source
+	switch {
+	case in.Cmd != nil: // not SourceExec, which would drop the context (and so the cancellation) of the command
+		source = gumbleffmpeg.SourceReader(&pipeline{in: in})
+	case in.Reader != nil:
+		source = gumbleffmpeg.SourceReader(in.Reader)
+	default:
+		source = gumbleffmpeg.SourceFile(in.Path)
+	}
+	stream := gumbleffmpeg.New(client, source)
+	stream.Offset = offset
+	return stream
+}
+
+// pipeline runs the input through an additional ffmpeg process applying the filter graph.
+// gumbleffmpeg builds its own ffmpeg arguments, so this is the only place filters can be inserted.
+// Without a filter the output of in.Cmd is read directly.
+// The processes are started lazily on the first Read, once the stream is actually played.
+type pipeline struct {
+	in     Input
+	filter string
+	offset time.Duration
+
+	once   sync.Once
+	mu     sync.Mutex
+	cmds   []*exec.Cmd
+	out    io.ReadCloser
+	err    error
+	closed bool
+}
+
+func (p *pipeline) start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		p.err = errors.New("pipeline closed")
+		return
+	}
+
+	if p.filter == "" && p.in.Cmd != nil {
+		if p.out, p.err = p.in.Cmd.StdoutPipe(); p.err != nil {
+			return
+		}
+		if p.err = p.in.Cmd.Start(); p.err == nil {
+			p.cmds = append(p.cmds, p.in.Cmd)
+		}
+		return
+	}
+
+	args := []string{"-hide_banner", "-loglevel", "error"}
+	if p.offset > 0 {
+		args = append(args, "-ss", strconv.FormatFloat(p.offset.Seconds(), 'f', -1, 64))
+	}
+	if p.in.Cmd == nil && p.in.Reader == nil {
+		args = append(args, "-i", p.in.Path)
+	} else {
+		args = append(args, "-i", "-")
+	}
+	args = append(args, "-af", p.filter, "-c:a", "pcm_s16le", "-f", "nut", "-")
+
+	ffmpeg := exec.Command("ffmpeg", args...)
+	switch {
+	case p.in.Cmd != nil:
+		stdout, err := p.in.Cmd.StdoutPipe()
+		if err != nil {
+			p.err = err
+			return
+		}
+		ffmpeg.Stdin = stdout
+		if p.err = p.in.Cmd.Start(); p.err != nil {
+			return
+		}
+		p.cmds = append(p.cmds, p.in.Cmd)
+	case p.in.Reader != nil:
+		ffmpeg.Stdin = p.in.Reader
+	}
+
+	if p.out, p.err = ffmpeg.StdoutPipe(); p.err != nil {
+		return
+	}
+	if p.err = ffmpeg.Start(); p.err != nil {
+		return
+	}
+	p.cmds = append(p.cmds, ffmpeg)
+}
+
+func (p *pipeline) Read(b []byte) (int, error) {
+	p.once.Do(p.start)
+	if p.err != nil {
+		return 0, p.err
+	}
+	return p.out.Read(b)
+}
+
+// Close kills every process of the pipeline and closes the input
+func (p *pipeline) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+
+	for _, cmd := range p.cmds {
+		if cmd.Process != nil {
+			cmd.Process.Kill() //#nosec G104 -- process may have already exited
+		}
+	}
+	for _, cmd := range p.cmds {
+		cmd.Wait() //#nosec G104 -- killed processes always report an error
+	}
+	if p.in.Reader != nil {
+		return p.in.Reader.Close()
+	}
+	return nil
+}
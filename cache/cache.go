@@ -0,0 +1,237 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/iotku/mumzic/youtubedl"
+)
+
+const Directory = "audiocache/" // Directory holding downloaded URL tracks
+
+// Files still being downloaded carry this prefix, they are ignored by lookups and eviction
+const tempPrefix = "tmp-"
+
+// maxSize is the most bytes the cache may use, 0 disables the cache
+var maxSize atomic.Int64
+
+var mu sync.Mutex
+var downloading = make(map[string]bool) // Keys (see key) of the URLs being downloaded
+
+// Enabled returns whether URL tracks should be cached
+func Enabled() bool {
+	return maxSize.Load() > 0
+}
+
+// MaxSize returns the most bytes the cache may use, 0 if it is disabled
+func MaxSize() int64 {
+	return maxSize.Load()
+}
+
+// Setup sets the size of the cache when the bot starts, removing files of downloads which never finished
+// and whatever doesn't fit anymore
+func Setup(size int64) {
+	if err := removeTemp(); err != nil {
+		log.Println("[cache] failed to remove unfinished downloads:", err)
+	}
+	Resize(size)
+}
+
+func key(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:])
+}
+
+// Lookup returns the cached file for url if there is one, marking it as recently used
+func Lookup(url string) (string, bool) {
+	if !Enabled() {
+		return "", false
+	}
+
+	path := filepath.Join(Directory, key(url)+".opus")
+	if _, err := os.Stat(path); err != nil {
+		return "", false
+	}
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); err != nil {
+		log.Println("[cache] failed to touch", path, err)
+	}
+	return path, true
+}
+
+// Store downloads url into the cache in the background, unless it is already cached or downloading
+func Store(url string) {
+	if !Enabled() {
+		return
+	}
+	if _, ok := Lookup(url); ok {
+		return
+	}
+
+	name := key(url)
+	mu.Lock()
+	if downloading[name] {
+		mu.Unlock()
+		return
+	}
+	downloading[name] = true
+	mu.Unlock()
+
+	go func() {
+		defer func() {
+			mu.Lock()
+			delete(downloading, name)
+			mu.Unlock()
+		}()
+		if err := download(url); err != nil {
+			log.Println("[cache] " + err.Error())
+			return
+		}
+		evict()
+	}()
+}
+
+func download(url string) error {
+	if err := os.MkdirAll(Directory, 0700); err != nil {
+		return err
+	}
+
+	name := key(url)
+	err := youtubedl.DownloadAudio(context.Background(), url, filepath.Join(Directory, tempPrefix+name+".%(ext)s"))
+	if err == nil {
+		err = os.Rename(filepath.Join(Directory, tempPrefix+name+".opus"), filepath.Join(Directory, name+".opus"))
+	}
+	if err != nil {
+		removeFiles(tempPrefix + name + ".*") // Partial downloads (yt-dlp may leave several)
+	}
+	return err
+}
+
+// removeFiles removes the files in Directory matching pattern
+func removeFiles(pattern string) {
+	paths, _ := filepath.Glob(filepath.Join(Directory, pattern)) // Only fails for malformed patterns
+	for _, v := range paths {
+		if err := os.Remove(v); err != nil {
+			log.Println("[cache] failed to remove", v, err)
+		}
+	}
+}
+
+// removeTemp removes the files of downloads which aren't in progress (e.g. from before a crash)
+func removeTemp() error {
+	dir, err := os.ReadDir(Directory)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, v := range dir {
+		name, ok := strings.CutPrefix(v.Name(), tempPrefix)
+		if !ok || v.IsDir() {
+			continue
+		}
+		if name, _, _ = strings.Cut(name, "."); downloading[name] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(Directory, v.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type entry struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+func entries() ([]entry, error) {
+	dir, err := os.ReadDir(Directory)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var files []entry
+	for _, v := range dir {
+		if v.IsDir() || strings.HasPrefix(v.Name(), tempPrefix) {
+			continue
+		}
+		info, err := v.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, entry{filepath.Join(Directory, v.Name()), info.Size(), info.ModTime()})
+	}
+	return files, nil
+}
+
+// evict removes the least recently used files until the cache fits in MaxSize
+func evict() {
+	size := maxSize.Load()
+	files, err := entries()
+	if err != nil {
+		log.Println("[cache] failed to read cache directory:", err)
+		return
+	}
+
+	var total int64
+	for _, v := range files {
+		total += v.size
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	for i := 0; total > size && i < len(files); i++ {
+		if err := os.Remove(files[i].path); err != nil {
+			log.Println("[cache] failed to evict", files[i].path, err)
+			continue
+		}
+		total -= files[i].size
+	}
+}
+
+// Resize changes MaxSize to size bytes, evicting the least recently used files which no longer fit
+func Resize(size int64) {
+	maxSize.Store(size)
+	evict()
+}
+
+// Stats returns how many tracks are cached and how many bytes they use
+func Stats() (count int, size int64, err error) {
+	files, err := entries()
+	for _, v := range files {
+		size += v.size
+	}
+	return len(files), size, err
+}
+
+// Clear removes every cached track, and the files of downloads which never finished
+func Clear() error {
+	if err := removeTemp(); err != nil {
+		return err
+	}
+	files, err := entries()
+	if err != nil {
+		return err
+	}
+	for _, v := range files {
+		if err := os.Remove(v.path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// chdirTemp runs the test in a temporary directory, Directory being relative
+func chdirTemp(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestEvict(t *testing.T) {
+	chdirTemp(t)
+	defer func(size int64) { maxSize.Store(size) }(maxSize.Load())
+	maxSize.Store(10)
+
+	if err := os.MkdirAll(Directory, 0700); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now().Add(-time.Hour)
+	urls := []string{"https://youtu.be/a", "https://youtu.be/b", "https://youtu.be/c", "https://youtu.be/d"}
+	for i, url := range urls { // a is the oldest
+		path := filepath.Join(Directory, key(url)+".opus")
+		if err := os.WriteFile(path, []byte("1234"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		modTime := start.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(Directory, tempPrefix+"e.opus"), []byte("12345678"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := Lookup(urls[0]); !ok { // Now the most recently used
+		t.Fatal("Lookup didn't find a cached file")
+	}
+	evict()
+
+	for i, expected := range []bool{true, false, false, true} {
+		if _, ok := Lookup(urls[i]); ok != expected {
+			t.Errorf("%s cached = %v after evict, want %v", urls[i], ok, expected)
+		}
+	}
+	if count, size, err := Stats(); err != nil || count != 2 || size != 8 {
+		t.Errorf("Stats = %d, %d, %v, want 2 files using 8 bytes", count, size, err)
+	}
+	if _, err := os.Stat(filepath.Join(Directory, tempPrefix+"e.opus")); err != nil {
+		t.Error("evict removed a file being downloaded:", err)
+	}
+}
+
+func TestRemoveTemp(t *testing.T) {
+	chdirTemp(t)
+	if err := os.MkdirAll(Directory, 0700); err != nil {
+		t.Fatal(err)
+	}
+	busy := key("https://youtu.be/busy")
+	mu.Lock()
+	downloading[busy] = true
+	mu.Unlock()
+	defer func() {
+		mu.Lock()
+		delete(downloading, busy)
+		mu.Unlock()
+	}()
+
+	files := map[string]bool{ // Whether the file is kept
+		tempPrefix + key("https://youtu.be/stale") + ".webm.part": false,
+		tempPrefix + key("https://youtu.be/stale") + ".opus":      false,
+		tempPrefix + busy + ".webm.part":                          true,
+		key("https://youtu.be/done") + ".opus":                    true,
+	}
+	for name := range files {
+		if err := os.WriteFile(filepath.Join(Directory, name), []byte("1234"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := removeTemp(); err != nil {
+		t.Fatal(err)
+	}
+	for name, kept := range files {
+		if _, err := os.Stat(filepath.Join(Directory, name)); (err == nil) != kept {
+			t.Errorf("%s kept = %v, want %v", name, err == nil, kept)
+		}
+	}
+}
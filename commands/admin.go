@@ -0,0 +1,56 @@
+package commands
+
+import (
+	"bufio"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/iotku/mumzic/playback"
+)
+
+// Admins are listed one per line as a certificate hash or the name of a registered user
+var adminsFile = "admins.txt"
+var admins []string
+
+func init() {
+	if err := LoadAdmins(); err != nil && !os.IsNotExist(err) {
+		log.Printf("failed to load admins: %v", err)
+	}
+}
+
+// LoadAdmins (re)loads the admin list from adminsFile
+func LoadAdmins() error {
+	f, err := os.Open(adminsFile) // #nosec G304 - Internal Helper method
+	if err != nil {
+		admins = nil
+		return err
+	}
+	defer f.Close()
+
+	var loaded []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") { // Ignore # comments
+			continue
+		}
+		loaded = append(loaded, line)
+	}
+	admins = loaded
+	return scanner.Err()
+}
+
+// isAdmin returns true if sender's certificate hash or (registered) name is in the admin list
+func isAdmin(player *playback.Player, sender string) bool {
+	user := player.Client.Users.Find(sender)
+	if user == nil {
+		return false
+	}
+	for _, v := range admins {
+		if v == user.Hash || (user.IsRegistered() && v == user.Name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/iotku/mumzic/audio"
+	"github.com/iotku/mumzic/helper"
+	"github.com/iotku/mumzic/playback"
+)
+
+var filterNames = []string{"mono", "tempo", "pitch"}
+
+func equalizer(player *playback.Player, sender string, isPrivate bool, arg string) {
+	settings := player.AudioSettings()
+	preset := strings.ToLower(arg)
+	if preset == "" {
+		current := settings.Equalizer
+		if current == "" {
+			current = "flat"
+		}
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Equalizer: <b>"+current+"</b> (Presets: "+
+			strings.Join(audio.PresetNames(), ", ")+")")
+		return
+	}
+
+	if _, ok := audio.Presets[preset]; !ok {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Unknown preset: Valid presets <b>"+
+			strings.Join(audio.PresetNames(), "|")+"</b>")
+		return
+	}
+	settings.Equalizer = preset
+	player.SetAudioSettings(settings)
+	helper.MsgDispatch(player.Client, isPrivate, sender, "Equalizer: <b>"+preset+"</b>")
+}
+
+// filter toggles audio filters, filters taking a multiplier are enabled with a value and disabled without one.
+func filter(player *playback.Player, sender string, isPrivate bool, arg string) {
+	settings := player.AudioSettings()
+	args := strings.Fields(strings.ToLower(arg))
+	if len(args) == 0 {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Filters: <b>"+describeFilters(settings)+"</b> (Available: "+
+			strings.Join(filterNames, ", ")+")")
+		return
+	}
+
+	var err error
+	switch name := args[0]; {
+	case name == "off" || name == "clear" || name == "none":
+		settings = audio.Settings{Equalizer: settings.Equalizer}
+	case len(args) > 1:
+		err = settings.Set(name, args[1])
+	case settings.IsSet(name):
+		err = settings.Unset(name)
+	default:
+		err = settings.Set(name, "")
+	}
+	if err != nil {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Filter not applied: "+err.Error())
+		return
+	}
+
+	player.SetAudioSettings(settings)
+	helper.MsgDispatch(player.Client, isPrivate, sender, "Filters: <b>"+describeFilters(settings)+"</b>")
+}
+
+func describeFilters(settings audio.Settings) string {
+	if filters := settings.Filters(); filters != "" {
+		return filters
+	}
+	return "none"
+}
+
+// speed changes the playback tempo without changing pitch, "!speed" alone shows the current speed
+func speed(player *playback.Player, sender string, isPrivate bool, arg string) {
+	settings := player.AudioSettings()
+	if arg == "" {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Current Speed: <b>"+
+			strconv.FormatFloat(settings.Speed(), 'f', -1, 64)+"x</b>")
+		return
+	}
+
+	var err error
+	if value := strings.ToLower(arg); value == "reset" || value == "normal" {
+		err = settings.Unset("tempo")
+	} else {
+		err = settings.Set("tempo", value)
+	}
+	if err != nil {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Invalid Speed: "+err.Error())
+		return
+	}
+
+	player.SetAudioSettings(settings)
+	helper.MsgDispatch(player.Client, isPrivate, sender, "Current Speed: <b>"+
+		strconv.FormatFloat(settings.Speed(), 'f', -1, 64)+"x</b>")
+}
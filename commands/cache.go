@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/iotku/mumzic/cache"
+	"github.com/iotku/mumzic/helper"
+	"github.com/iotku/mumzic/playback"
+)
+
+// cacheCommand handles the admin only "!cache stats", "!cache clear" and "!cache size [MiB]"
+func cacheCommand(player *playback.Player, sender string, isPrivate bool, arg string) {
+	if !isAdmin(player, sender) {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Only admins may manage the cache.")
+		return
+	}
+	if command, size, _ := strings.Cut(strings.ToLower(arg), " "); command == "size" {
+		cacheSize(player, sender, isPrivate, strings.TrimSpace(size))
+		return
+	}
+	if !cache.Enabled() {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "The download cache is disabled, use <b>cache size</b> to enable it.")
+		return
+	}
+
+	switch strings.ToLower(arg) {
+	case "", "stats":
+		count, size, err := cache.Stats()
+		if err != nil {
+			helper.MsgDispatch(player.Client, isPrivate, sender, "Cache Error: "+err.Error())
+			return
+		}
+		helper.MsgDispatch(player.Client, isPrivate, sender, fmt.Sprintf("Cache: <b>%d</b> track(s) using <b>%.1f</b> / %d MiB",
+			count, float64(size)/(1<<20), cache.MaxSize()>>20))
+	case "clear":
+		if err := cache.Clear(); err != nil {
+			helper.MsgDispatch(player.Client, isPrivate, sender, "Cache Error: "+err.Error())
+			return
+		}
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Cache cleared.")
+	default:
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Usage: cache [stats|clear|size]")
+	}
+}
+
+// cacheSize shows the size of the cache or changes it to size MiB, 0 disabling the cache
+func cacheSize(player *playback.Player, sender string, isPrivate bool, size string) {
+	if size != "" {
+		mib, err := strconv.Atoi(size)
+		if err != nil || mib < 0 {
+			helper.MsgDispatch(player.Client, isPrivate, sender, "Usage: cache size [MiB], 0 disables the cache")
+			return
+		}
+		cache.Resize(int64(mib) << 20)
+		player.Config.CacheSize = mib
+		player.Config.Save()
+	}
+
+	if !cache.Enabled() {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "The download cache is disabled.")
+		return
+	}
+	helper.MsgDispatch(player.Client, isPrivate, sender, fmt.Sprintf("Cache size: <b>%d</b> MiB", cache.MaxSize()>>20))
+}
@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"html"
+	"strconv"
+
+	"github.com/iotku/mumzic/helper"
+	"github.com/iotku/mumzic/messages"
+	"github.com/iotku/mumzic/playback"
+)
+
+func chapters(player *playback.Player, sender string, isPrivate bool) {
+	chapters := player.Chapters()
+	if !player.IsPlaying() || len(chapters) == 0 {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "The current track has no chapters.")
+		return
+	}
+
+	current := player.CurrentChapter()
+	rows := make([]string, 0, len(chapters))
+	for i, v := range chapters {
+		row := html.EscapeString(v.Title) + " (" + messages.FormatDuration(v.Start) + ")"
+		if i == current {
+			row = "<b>" + row + "</b>"
+		}
+		rows = append(rows, row)
+	}
+
+	output := messages.MakeTable("Chapters")
+	messages.SaveMoreRows(sender, player.Config.MaxLines, rows, output)
+	output.AddRow("---")
+	output.AddRow("Use <b>chapter [#]</b> to jump to a chapter.")
+	helper.MsgDispatch(player.Client, isPrivate, sender, output.String())
+}
+
+func chapter(player *playback.Player, sender string, isPrivate bool, arg string) {
+	index, err := strconv.Atoi(arg)
+	if err != nil {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Usage: chapter [#] (see <b>chapters</b>)")
+		return
+	}
+	if !player.IsPlaying() {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Not Playing.")
+		return
+	}
+	if err := player.SeekChapter(index); err != nil {
+		helper.MsgDispatch(player.Client, isPrivate, sender, err.Error())
+	}
+}
+
+// skipChapter jumps to the start of the next chapter of the current track
+func skipChapter(player *playback.Player, sender string, isPrivate bool) {
+	if !player.IsPlaying() || len(player.Chapters()) == 0 {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "The current track has no chapters.")
+		return
+	}
+	next := player.CurrentChapter() + 1
+	if next >= len(player.Chapters()) {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "This is the last chapter, use <b>skip</b> for the next track.")
+		return
+	}
+	if err := player.SeekChapter(next); err != nil {
+		helper.MsgDispatch(player.Client, isPrivate, sender, err.Error())
+	}
+}
@@ -1,7 +1,9 @@
 package commands
 
 import (
+	"context"
 	"fmt"
+	"html"
 	"math"
 	"strconv"
 	"strings"
@@ -10,38 +12,66 @@ import (
 	"github.com/iotku/mumzic/helper"
 	"github.com/iotku/mumzic/messages"
 	"github.com/iotku/mumzic/playback"
+	"github.com/iotku/mumzic/playlist"
 	"github.com/iotku/mumzic/search"
 	"github.com/iotku/mumzic/youtubedl"
 )
 
+// Most entries queued from a single playlist URL
+const maxPlaylistEntries = 100
+
 func IsCommand(message string, isPrivate bool, username string, config *database.Config) bool {
 	message = strings.TrimSpace(message)
 	return strings.HasPrefix(message, config.Prefix) || strings.HasPrefix(message, username) || isPrivate
 }
 
 func CommandDispatch(player *playback.Player, msg string, isPrivate bool, sender string) {
-	helper.DebugPrintln("IsPlaying:", player.IsPlaying(), "Len:", len(player.Playlist.Playlist), "Count", player.Playlist.Count(), "PlPos:", player.Playlist.Position, "HasNext:", player.Playlist.HasNext())
+	helper.DebugPrintln("IsPlaying:", player.IsPlaying(), "Len:", player.Playlist.Size(), "Count", player.Playlist.Count(), "PlPos:", player.Playlist.GetPosition(), "HasNext:", player.Playlist.HasNext())
 	command, arg := getCommandAndArg(msg, player.Client.Self.Name, player.Config)
 
 	switch command {
 	case "play", "add":
 		play(arg, sender, isPrivate, player)
+	case "playall", "addall":
+		playAll(arg, sender, isPrivate, player)
 	case "playnow":
 		playNow(player, sender, isPrivate, arg)
 	case "playnext", "addnext":
-		err := player.Playlist.AddNext(arg)
-		if err != nil {
+		err := player.Playlist.AddNext(arg, sender)
+		if err != nil && !playlist.IsWarning(err) {
 			helper.MsgDispatch(player.Client, isPrivate, sender, "Not Added: "+err.Error())
 			return
 		}
 
-		helper.MsgDispatch(player.Client, isPrivate, sender, "Added: "+player.Playlist.GetNextHuman())
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Added: "+player.Playlist.GetNextHuman()+duplicateNote(err))
 	case "stop":
+		if player.IsPlaying() {
+			player.Playlist.Checkpoint("stop")
+		}
 		player.Stop(true)
+	case "clear":
+		removed := player.Playlist.Clear()
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Cleared "+strconv.Itoa(removed)+" upcoming track(s), use <b>undo</b> to restore them.")
+	case "undo":
+		undo(player, sender, isPrivate)
+	case "dedupe":
+		dedupe(player, sender, isPrivate, arg)
 	case "skip", "next":
 		skip(player, sender, isPrivate, arg)
 	case "vol", "volume":
 		vol(player, sender, isPrivate, arg)
+	case "speed":
+		speed(player, sender, isPrivate, arg)
+	case "np", "nowplaying":
+		nowPlaying(player, sender, isPrivate)
+	case "station":
+		station(player, sender, isPrivate, arg)
+	case "stations":
+		stations(player, sender, isPrivate)
+	case "eq", "equalizer":
+		equalizer(player, sender, isPrivate, arg)
+	case "filter", "filters":
+		filter(player, sender, isPrivate, arg)
 	case "list":
 		list(player, sender, isPrivate)
 	case "retarget":
@@ -56,14 +86,46 @@ func CommandDispatch(player *playback.Player, msg string, isPrivate bool, sender
 	case "rand", "random":
 		rand(player, sender, isPrivate, arg)
 	case "radio":
-		toggleRadio(player, sender, isPrivate)
+		radio(player, sender, isPrivate, arg)
+	case "autoplay":
+		autoplay(player, sender, isPrivate, arg)
 	case "search", "find":
 		find(player, sender, isPrivate, arg)
+	case "pl", "playlist":
+		savedPlaylist(player, sender, isPrivate, arg)
+	case "chapters":
+		chapters(player, sender, isPrivate)
+	case "chapter":
+		chapter(player, sender, isPrivate, arg)
+	case "history":
+		history(player, sender, isPrivate, arg)
+	case "replay":
+		replay(player, sender, isPrivate, arg)
+	case "like":
+		like(player, sender, isPrivate)
+	case "unlike":
+		unlike(player, sender, isPrivate, arg)
+	case "favorites", "favs":
+		favorites(player, sender, isPrivate)
+	case "stats":
+		stats(player, sender, isPrivate, arg)
+	case "yt", "youtube":
+		youtubeSearch(player, sender, isPrivate, arg)
+	case "pick":
+		pick(player, sender, isPrivate, arg)
 	case "saveconf":
 		player.Config.Channel = player.Client.Self.Channel.Name
 		player.Config.Save()
 	case "reload":
-		helper.LogErr(youtubedl.LoadAllowedURLPrefixesFromFile(), "YTDL Reload")
+		reload(player, sender, isPrivate)
+	case "cache":
+		cacheCommand(player, sender, isPrivate, arg)
+	case "maxduration":
+		maxDuration(player, sender, isPrivate, arg)
+	case "import":
+		importPlaylist(player, sender, isPrivate, arg)
+	case "export":
+		exportPlaylist(player, sender, isPrivate, arg)
 	case "more":
 		helper.MsgDispatch(player.Client, isPrivate, sender, messages.GetMoreTable(sender, player.Config.MaxLines))
 	case "less":
@@ -76,8 +138,17 @@ func CommandDispatch(player *playback.Player, msg string, isPrivate bool, sender
 }
 
 func skip(player *playback.Player, sender string, isPrivate bool, arg string) {
+	if strings.ToLower(arg) == "chapter" {
+		skipChapter(player, sender, isPrivate)
+		return
+	}
 	if player.IsRadio {
-		playNow(player, sender, isPrivate, strconv.Itoa(search.GetRandomTrackIDs(1)[0]))
+		id, err := player.RadioTrackID()
+		if err != nil {
+			helper.MsgDispatch(player.Client, isPrivate, sender, "Radio: "+html.EscapeString(err.Error()))
+			return
+		}
+		playNow(player, sender, isPrivate, strconv.Itoa(id))
 		return
 	}
 
@@ -90,19 +161,68 @@ func skip(player *playback.Player, sender string, isPrivate bool, arg string) {
 }
 
 func playNow(player *playback.Player, sender string, isPrivate bool, track string) {
-	err := player.PlayNow(track)
-	if err != nil {
+	err := player.PlayNow(track, sender)
+	if err != nil && !playlist.IsWarning(err) {
 		helper.MsgDispatch(player.Client, isPrivate, sender, "Not Added: "+err.Error())
 		return
 	}
 }
 
+func nowPlaying(player *playback.Player, sender string, isPrivate bool) {
+	if player.Playlist.IsEmpty() || !player.IsPlaying() {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Not Playing.")
+		return
+	}
+	helper.MsgDispatch(player.Client, isPrivate, sender, player.NowPlayingElapsed())
+}
+
+// radio handles "!radio [filter]": without a filter it toggles radio mode, with one (see search.ParseQuery)
+// it enables radio mode playing only the matching tracks
+func radio(player *playback.Player, sender string, isPrivate bool, arg string) {
+	switch strings.ToLower(arg) {
+	case "":
+		toggleRadio(player, sender, isPrivate)
+		return
+	case "off":
+		if player.IsRadio {
+			toggleRadio(player, sender, isPrivate)
+		}
+		return
+	}
+
+	filter, err := search.ParseQuery(arg)
+	if err != nil {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Invalid filter: "+html.EscapeString(err.Error()))
+		return
+	}
+	previous := player.RadioFilter
+	player.RadioFilter = filter
+	id, err := player.RadioTrackID()
+	if err != nil {
+		player.RadioFilter = previous
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Radio: "+html.EscapeString(err.Error()))
+		return
+	}
+
+	player.IsRadio = true
+	helper.MsgDispatch(player.Client, isPrivate, sender, "Enabled Radio Mode, Shuffling <b>"+html.EscapeString(filter.String())+"</b> forever.")
+	if !player.IsPlaying() {
+		playNow(player, sender, isPrivate, strconv.Itoa(id))
+	}
+}
+
 func toggleRadio(player *playback.Player, sender string, isPrivate bool) {
 	if !player.IsRadio {
+		player.RadioFilter = search.Query{}
+		id, err := player.RadioTrackID()
+		if err != nil {
+			helper.MsgDispatch(player.Client, isPrivate, sender, "Radio: "+html.EscapeString(err.Error()))
+			return
+		}
 		helper.MsgDispatch(player.Client, isPrivate, sender, "Enabled Radio Mode, Shuffling forever.")
 		player.IsRadio = true
 		if !player.IsPlaying() {
-			playNow(player, sender, isPrivate, strconv.Itoa(search.GetRandomTrackIDs(1)[0]))
+			playNow(player, sender, isPrivate, strconv.Itoa(id))
 		}
 	} else {
 		player.IsRadio = false
@@ -110,6 +230,66 @@ func toggleRadio(player *playback.Player, sender string, isPrivate bool) {
 	}
 }
 
+// undo handles "!undo", restoring the queue from before the last skip, stop, clear, playnow or playlist load
+func undo(player *playback.Player, sender string, isPrivate bool) {
+	var current string
+	if player.IsPlaying() {
+		current = player.Playlist.GetCurrentPath()
+	}
+	action, ok := player.Playlist.Undo()
+	if !ok {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Nothing to undo.")
+		return
+	}
+	helper.MsgDispatch(player.Client, isPrivate, sender, "Undid <b>"+action+"</b>, "+strconv.Itoa(player.Playlist.Count())+" track(s) queued.")
+
+	if player.Playlist.IsEmpty() || (current != "" && player.Playlist.GetCurrentPath() == current) {
+		return // Still on the track that's playing
+	}
+	if player.IsRadio {
+		toggleRadio(player, sender, isPrivate)
+	}
+	player.Stop(true)
+	player.PlayCurrent()
+}
+
+// autoplay handles "!autoplay [on/off]", toggling it without an argument
+func autoplay(player *playback.Player, sender string, isPrivate bool, arg string) {
+	switch strings.ToLower(arg) {
+	case "":
+		player.Autoplay = !player.Autoplay
+	case "on":
+		player.Autoplay = true
+	case "off":
+		player.Autoplay = false
+	default:
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Usage: autoplay [on/off]")
+		return
+	}
+
+	if player.Autoplay {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Enabled Autoplay, similar tracks play when the queue runs out.")
+	} else {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Disabled Autoplay.")
+	}
+}
+
+// reload rereads whitelist.txt and the admin list, telling the sender about rules that failed to parse
+func reload(player *playback.Player, sender string, isPrivate bool) {
+	helper.LogErr(LoadAdmins(), "Admins Reload")
+	err := youtubedl.LoadWhitelist()
+	if err == nil {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Reloaded whitelist and admins.")
+		return
+	}
+	helper.LogErr(err, "YTDL Reload")
+	output := messages.MakeTable("Whitelist errors")
+	for _, line := range strings.Split(err.Error(), "\n") {
+		output.AddRow(html.EscapeString(line))
+	}
+	helper.MsgDispatch(player.Client, isPrivate, sender, output.String())
+}
+
 func joinUserChannel(player *playback.Player, sender string) {
 	client := player.Client
 	user := client.Users.Find(sender)
@@ -150,18 +330,64 @@ func play(id string, sender string, isPrivate bool, player *playback.Player) {
 		return
 	}
 
+	if strings.EqualFold(id, "favorites") {
+		playFavorites(player, sender, isPrivate)
+		return
+	}
+
+	if youtubedl.IsPlaylistURL(helper.StripHTMLTags(id)) {
+		playAll(id, sender, isPrivate, player)
+		return
+	}
+
 	var playNext bool
 	if !player.Playlist.IsEmpty() && !player.Playlist.HasNext() {
 		playNext = true
 	}
 
-	human, err := player.Playlist.AddToQueue(id)
-	if err != nil {
+	human, err := player.Playlist.AddToQueue(id, sender)
+	if err != nil && !playlist.IsWarning(err) {
 		helper.MsgDispatch(player.Client, isPrivate, sender, err.Error())
 		return
 	}
-	helper.MsgDispatch(player.Client, isPrivate, sender, "Queued: "+human)
+	helper.MsgDispatch(player.Client, isPrivate, sender, "Queued: "+human+duplicateNote(err))
+	startQueued(player, sender, isPrivate, playNext)
+}
 
+// playAll queues every entry (up to maxPlaylistEntries) of a YouTube or SoundCloud playlist
+func playAll(url string, sender string, isPrivate bool, player *playback.Player) {
+	url = helper.StripHTMLTags(url)
+	if !strings.HasPrefix(url, "http") || !youtubedl.IsWhiteListedURL(url) {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "URL Doesn't meet whitelist")
+		return
+	}
+
+	var playNext bool
+	if !player.Playlist.IsEmpty() && !player.Playlist.HasNext() {
+		playNext = true
+	}
+
+	title, entries, err := youtubedl.GetPlaylist(context.Background(), url, maxPlaylistEntries)
+	if err != nil {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Not Added: "+err.Error())
+		return
+	}
+	added, duplicates := player.Playlist.QueuePlaylist(entries, sender)
+	if added == 0 && duplicates != 0 {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Not Added: every track is already queued or longer than the limit")
+		return
+	} else if added == 0 {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Not Added: every track is longer than the limit")
+		return
+	}
+	helper.MsgDispatch(player.Client, isPrivate, sender, fmt.Sprintf("Queued <b>%d</b> track(s) from: %s", added, html.EscapeString(title))+
+		duplicatesNote(duplicates))
+	startQueued(player, sender, isPrivate, playNext)
+}
+
+// startQueued starts playback after adding to the queue, playNext should be true when the playlist was
+// not empty but had nothing after the current track.
+func startQueued(player *playback.Player, sender string, isPrivate bool, playNext bool) {
 	if player.IsRadio {
 		toggleRadio(player, sender, isPrivate)
 	}
@@ -191,12 +417,23 @@ func list(player *playback.Player, sender string, isPrivate bool) {
 	messages.SaveMoreRows(sender, player.Config.MaxLines, playlist, output)
 	output.AddRow("---")
 	output.AddRow(strconv.Itoa(player.Playlist.Count()) + " Track(s) queued.")
+	if total, unknown := player.QueueDuration(); total > 0 {
+		eta := "About <b>" + messages.FormatDuration(total) + "</b> left"
+		if unknown > 0 {
+			eta += " (plus " + strconv.Itoa(unknown) + " live or unknown length track(s))"
+		}
+		output.AddRow(eta + ".")
+	}
 
 	helper.MsgDispatch(player.Client, isPrivate, sender, output.String())
 }
 
 func find(player *playback.Player, sender string, isPrivate bool, arg string) {
-	results := search.FindArtistTitle(arg)
+	results, err := search.Find(arg)
+	if err != nil {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Invalid search: "+html.EscapeString(err.Error()))
+		return
+	}
 
 	output := messages.MakeTable("Search Results")
 	messages.SaveMoreRows(sender, player.Config.MaxLines, results, output)
@@ -218,9 +455,11 @@ func rand(player *playback.Player, sender string, isPrivate bool, arg string) {
 	output := messages.MakeTable("Randomly Added")
 	idList := search.GetRandomTrackIDs(value)
 	for _, v := range idList {
-		human := player.Playlist.QueueID(v)
-		if human != "" {
-			output.AddRow("Added: <b>" + human + "</b>")
+		human, err := player.Playlist.QueueID(v, sender)
+		if err != nil && !playlist.IsWarning(err) {
+			output.AddRow("Skipped: <b>" + human + "</b> (already queued)")
+		} else if human != "" {
+			output.AddRow("Added: <b>" + human + "</b>" + duplicateNote(err))
 		} else {
 			output.AddRow("Error: <b>" + "failed to add ID#" + strconv.Itoa(v) + "</b>")
 		}
@@ -71,3 +71,28 @@ func TestPlayNowAlias(t *testing.T) {
 		t.Errorf("got %q %q, wanted %q %q", got1, got2, "p", "song name")
 	}
 }
+
+func TestParseStatsArgs(t *testing.T) {
+	tests := []struct {
+		arg                      string
+		wantGrouping, wantWindow string
+		wantOK                   bool
+	}{
+		{"", "", "all", true},
+		{"week", "", "week", true},
+		{"tracks", "tracks", "all", true},
+		{"artists day", "artists", "day", true},
+		{"Day Requesters", "requesters", "day", true},
+		{"tracks artists", "", "", false},
+		{"day week", "", "", false},
+		{"songs", "", "", false},
+		{"tracks day extra", "", "", false},
+	}
+
+	for _, tt := range tests {
+		grouping, window, ok := parseStatsArgs(tt.arg)
+		if grouping != tt.wantGrouping || window != tt.wantWindow || ok != tt.wantOK {
+			t.Errorf("parseStatsArgs(%q) = %q, %q, %v, want %q, %q, %v", tt.arg, grouping, window, ok, tt.wantGrouping, tt.wantWindow, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/iotku/mumzic/helper"
+	"github.com/iotku/mumzic/playback"
+	"github.com/iotku/mumzic/playlist"
+)
+
+// duplicateNote returns a note for the reply to a track added despite being queued already, "" for other errors
+func duplicateNote(err error) string {
+	if playlist.IsWarning(err) {
+		return " (already queued)"
+	}
+	return ""
+}
+
+// duplicatesNote returns a note for the reply to adding several tracks, of which duplicates were left out
+func duplicatesNote(duplicates int) string {
+	if duplicates == 0 {
+		return ""
+	}
+	return " (" + strconv.Itoa(duplicates) + " already queued, left out)"
+}
+
+// dedupe handles "!dedupe", removing duplicate upcoming tracks, and "!dedupe policy [reject/warn/allow]"
+func dedupe(player *playback.Player, sender string, isPrivate bool, arg string) {
+	args := strings.Fields(strings.ToLower(arg))
+	if len(args) == 0 {
+		removed := player.Playlist.Dedupe()
+		if removed == 0 {
+			helper.MsgDispatch(player.Client, isPrivate, sender, "No duplicates queued.")
+			return
+		}
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Removed "+strconv.Itoa(removed)+" duplicate(s), use <b>undo</b> to restore them.")
+		return
+	}
+	if args[0] != "policy" || len(args) > 2 {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Usage: dedupe or dedupe policy ["+strings.Join(playlist.DuplicatePolicies, "/")+"]")
+		return
+	}
+
+	if len(args) == 1 {
+		policy := player.Playlist.Duplicates
+		if policy == "" {
+			policy = playlist.DuplicatesAllow
+		}
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Duplicate policy: <b>"+policy+"</b>")
+		return
+	}
+	if !isAdmin(player, sender) {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Only admins may change the duplicate policy.")
+		return
+	}
+	policy := args[1]
+	for _, v := range playlist.DuplicatePolicies {
+		if v == policy {
+			player.Playlist.Duplicates = policy
+			player.Config.Duplicates = policy
+			player.Config.Save()
+			helper.MsgDispatch(player.Client, isPrivate, sender, "Duplicate policy: <b>"+policy+"</b>")
+			return
+		}
+	}
+	helper.MsgDispatch(player.Client, isPrivate, sender, "Invalid policy, use one of: "+strings.Join(playlist.DuplicatePolicies, ", "))
+}
@@ -0,0 +1,82 @@
+package commands
+
+import (
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/iotku/mumzic/database"
+	"github.com/iotku/mumzic/helper"
+	"github.com/iotku/mumzic/messages"
+	"github.com/iotku/mumzic/playback"
+	"github.com/iotku/mumzic/playlist"
+)
+
+const (
+	defaultHistory = 10
+	maxHistory     = 50
+)
+
+// history handles "!history [#]", listing the most recently played tracks
+func history(player *playback.Player, sender string, isPrivate bool, arg string) {
+	amount := defaultHistory
+	if arg != "" {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n <= 0 {
+			helper.MsgDispatch(player.Client, isPrivate, sender, "Usage: history [#] (up to "+strconv.Itoa(maxHistory)+")")
+			return
+		}
+		amount = min(n, maxHistory)
+	}
+
+	entries := database.GetHistory(player.Config.Hostname, amount)
+	if len(entries) == 0 {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Nothing has been played yet.")
+		return
+	}
+
+	rows := make([]string, 0, len(entries))
+	for _, v := range entries {
+		requester := "radio"
+		if v.Requester != "" {
+			requester = html.EscapeString(v.Requester)
+		}
+		rows = append(rows, html.EscapeString(v.Human)+" ("+requester+", "+v.Started.Format("Jan 2 15:04")+", "+v.Outcome+")")
+	}
+
+	output := messages.MakeTable("History")
+	messages.SaveMoreRows(sender, player.Config.MaxLines, rows, output)
+	output.AddRow("---")
+	output.AddRow("Use <b>replay [#]</b> to queue a track again.")
+	helper.MsgDispatch(player.Client, isPrivate, sender, output.String())
+}
+
+// replay handles "!replay <#>", queueing a track from history again
+func replay(player *playback.Player, sender string, isPrivate bool, arg string) {
+	index, err := strconv.Atoi(arg)
+	if err != nil || index < 0 || index >= maxHistory {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Usage: replay [#] (see <b>history</b>)")
+		return
+	}
+	entries := database.GetHistory(player.Config.Hostname, index+1)
+	if index >= len(entries) {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "No such track in history.")
+		return
+	}
+	entry := entries[index]
+
+	playNext := !player.Playlist.IsEmpty() && !player.Playlist.HasNext()
+	human := entry.Human
+	if strings.HasPrefix(entry.Path, "http") {
+		human, err = player.Playlist.AddToQueue(entry.Path, sender) // Checks the whitelist again
+		if err != nil && !playlist.IsWarning(err) {
+			helper.MsgDispatch(player.Client, isPrivate, sender, "Not Queued: "+html.EscapeString(err.Error()))
+			return
+		}
+	} else if player.Playlist.AddTracks([][]string{{entry.Path, entry.Human}}, sender) != 0 {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Not Queued: "+html.EscapeString((&playlist.DuplicateError{Human: entry.Human}).Error()))
+		return
+	}
+	helper.MsgDispatch(player.Client, isPrivate, sender, "Queued: "+html.EscapeString(human)+duplicateNote(err))
+	startQueued(player, sender, isPrivate, playNext)
+}
@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"html"
+	"strconv"
+
+	"github.com/iotku/mumzic/database"
+	"github.com/iotku/mumzic/helper"
+	"github.com/iotku/mumzic/messages"
+	"github.com/iotku/mumzic/playback"
+)
+
+// like handles "!like", adding the current track to the favorites of sender
+func like(player *playback.Player, sender string, isPrivate bool) {
+	owner, ok := userKey(player, sender)
+	if !ok {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Not Liked: you need a certificate or registration to like tracks")
+		return
+	}
+	if !player.IsPlaying() {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Nothing is playing.")
+		return
+	}
+
+	path, human := player.Playlist.GetCurrentPath(), player.Playlist.GetCurrentHuman()
+	if !database.AddLike(player.Config.Hostname, owner, path, human) {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "You already like <b>"+html.EscapeString(human)+"</b>")
+		return
+	}
+	helper.MsgDispatch(player.Client, isPrivate, sender, "Liked: <b>"+html.EscapeString(human)+"</b>")
+}
+
+// unlike handles "!unlike [#]", removing the current track, or the given one of favorites, from the favorites of sender
+func unlike(player *playback.Player, sender string, isPrivate bool, arg string) {
+	owner, ok := userKey(player, sender)
+	if !ok {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "You need a certificate or registration to like tracks")
+		return
+	}
+
+	var path, human string
+	if arg == "" {
+		if !player.IsPlaying() {
+			helper.MsgDispatch(player.Client, isPrivate, sender, "Nothing is playing, use <b>unlike [#]</b> (see <b>favorites</b>)")
+			return
+		}
+		path, human = player.Playlist.GetCurrentPath(), player.Playlist.GetCurrentHuman()
+	} else {
+		index, err := strconv.Atoi(arg)
+		likes := database.GetLikes(player.Config.Hostname, owner, false)
+		if err != nil || index < 0 || index >= len(likes) {
+			helper.MsgDispatch(player.Client, isPrivate, sender, "Usage: unlike [#] (see <b>favorites</b>)")
+			return
+		}
+		path, human = likes[index][0], likes[index][1]
+	}
+
+	if !database.RemoveLike(player.Config.Hostname, owner, path) {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "You don't like <b>"+html.EscapeString(human)+"</b>")
+		return
+	}
+	helper.MsgDispatch(player.Client, isPrivate, sender, "Unliked: <b>"+html.EscapeString(human)+"</b>")
+}
+
+// favorites handles "!favorites", listing the tracks sender likes
+func favorites(player *playback.Player, sender string, isPrivate bool) {
+	owner, ok := userKey(player, sender)
+	if !ok {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "You need a certificate or registration to like tracks")
+		return
+	}
+	likes := database.GetLikes(player.Config.Hostname, owner, false)
+	if len(likes) == 0 {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "You haven't liked any tracks, use <b>like</b> while one is playing.")
+		return
+	}
+
+	rows := make([]string, 0, len(likes))
+	for _, v := range likes {
+		rows = append(rows, html.EscapeString(v[1]))
+	}
+	output := messages.MakeTable("Favorites")
+	messages.SaveMoreRows(sender, player.Config.MaxLines, rows, output)
+	output.AddRow("---")
+	output.AddRow("Use <b>play favorites</b> to queue them shuffled.")
+	helper.MsgDispatch(player.Client, isPrivate, sender, output.String())
+}
+
+// playFavorites handles "!play favorites", queueing the tracks sender likes in random order
+func playFavorites(player *playback.Player, sender string, isPrivate bool) {
+	owner, ok := userKey(player, sender)
+	if !ok {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "You need a certificate or registration to like tracks")
+		return
+	}
+	likes := database.GetLikes(player.Config.Hostname, owner, true)
+	if len(likes) == 0 {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "You haven't liked any tracks, use <b>like</b> while one is playing.")
+		return
+	}
+
+	playNext := !player.Playlist.IsEmpty() && !player.Playlist.HasNext()
+	duplicates := player.Playlist.AddTracks(likes, sender)
+	helper.MsgDispatch(player.Client, isPrivate, sender, "Queued "+strconv.Itoa(len(likes)-duplicates)+" favorite(s)"+duplicatesNote(duplicates))
+	startQueued(player, sender, isPrivate, playNext)
+}
@@ -0,0 +1,41 @@
+package commands
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iotku/mumzic/helper"
+	"github.com/iotku/mumzic/playback"
+)
+
+// maxDuration handles "!maxduration", showing the longest URL track which may be queued, and "!maxduration [minutes]"
+func maxDuration(player *playback.Player, sender string, isPrivate bool, arg string) {
+	arg = strings.TrimSpace(arg)
+	if arg == "" {
+		if player.Config.MaxDuration == 0 {
+			helper.MsgDispatch(player.Client, isPrivate, sender, "URL tracks of any length may be queued.")
+			return
+		}
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Longest URL track: <b>"+strconv.Itoa(player.Config.MaxDuration)+"</b> minute(s)")
+		return
+	}
+	if !isAdmin(player, sender) {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Only admins may change the track length limit.")
+		return
+	}
+
+	minutes, err := strconv.Atoi(arg)
+	if err != nil || minutes < 0 {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Usage: maxduration [minutes], 0 for no limit")
+		return
+	}
+	player.Playlist.MaxDuration = time.Duration(minutes) * time.Minute
+	player.Config.MaxDuration = minutes
+	player.Config.Save()
+	if minutes == 0 {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "URL tracks of any length may be queued.")
+		return
+	}
+	helper.MsgDispatch(player.Client, isPrivate, sender, "Longest URL track: <b>"+strconv.Itoa(minutes)+"</b> minute(s)")
+}
@@ -0,0 +1,121 @@
+package commands
+
+import (
+	"html"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/iotku/mumzic/database"
+	"github.com/iotku/mumzic/helper"
+	"github.com/iotku/mumzic/playback"
+	"github.com/iotku/mumzic/playlist"
+)
+
+// playlistFile returns the path of file within playlist.FilesDirectory, rejecting anything outside of it
+func playlistFile(file string) (string, error) {
+	if file == "" || file != filepath.Base(file) || strings.HasPrefix(file, ".") {
+		return "", os.ErrInvalid
+	}
+	if _, err := playlist.FormatOf(file); err != nil {
+		return "", err
+	}
+	return filepath.Join(playlist.FilesDirectory, file), nil
+}
+
+// importPlaylist handles "!import <file> [name]": the file is queued, or saved as the playlist called name
+func importPlaylist(player *playback.Player, sender string, isPrivate bool, arg string) {
+	if !isAdmin(player, sender) {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Only admins may import playlists.")
+		return
+	}
+	args := strings.Fields(arg)
+	if len(args) == 0 || len(args) > 2 {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Usage: import [file] [playlist name], files are read from "+playlist.FilesDirectory)
+		return
+	}
+	path, err := playlistFile(args[0])
+	if err != nil {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Invalid file: "+err.Error())
+		return
+	}
+
+	tracks, skipped, err := playlist.Import(path)
+	if err != nil {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Not Imported: "+html.EscapeString(err.Error()))
+		return
+	}
+	result := "Imported " + strconv.Itoa(len(tracks)) + " track(s)"
+	if len(skipped) != 0 {
+		result += ", skipped " + strconv.Itoa(len(skipped)) + " not in the library or not whitelisted"
+	}
+	if len(tracks) == 0 {
+		helper.MsgDispatch(player.Client, isPrivate, sender, result)
+		return
+	}
+
+	if len(args) == 1 {
+		playNext := !player.Playlist.IsEmpty() && !player.Playlist.HasNext()
+		duplicates := player.Playlist.AddTracks(tracks, sender)
+		helper.MsgDispatch(player.Client, isPrivate, sender, result+" into the queue"+duplicatesNote(duplicates))
+		startQueued(player, sender, isPrivate, playNext)
+		return
+	}
+
+	name := strings.ToLower(args[1])
+	if !nameRegex.MatchString(name) || name == "list" {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Invalid playlist name: use letters, numbers, - and _")
+		return
+	}
+	owner, _ := userKey(player, sender)
+	if err := database.SavePlaylist(player.Config.Hostname, name, owner, sender, tracks, false); err != nil {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Not Saved: "+err.Error())
+		return
+	}
+	helper.MsgDispatch(player.Client, isPrivate, sender, result+" as <b>"+name+"</b>")
+}
+
+// exportPlaylist handles "!export <file> [name]": the queue, or the saved playlist called name, is written to the file
+func exportPlaylist(player *playback.Player, sender string, isPrivate bool, arg string) {
+	if !isAdmin(player, sender) {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Only admins may export playlists.")
+		return
+	}
+	args := strings.Fields(arg)
+	if len(args) == 0 || len(args) > 2 {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Usage: export [file] [playlist name], files are written to "+playlist.FilesDirectory)
+		return
+	}
+	path, err := playlistFile(args[0])
+	if err != nil {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Invalid file: "+err.Error())
+		return
+	}
+
+	var tracks [][]string
+	if len(args) == 1 {
+		tracks = player.Playlist.Upcoming()
+	} else {
+		name := strings.ToLower(args[1])
+		if _, err := database.GetPlaylist(player.Config.Hostname, name); err != nil {
+			helper.MsgDispatch(player.Client, isPrivate, sender, err.Error())
+			return
+		}
+		tracks = database.GetPlaylistTracks(player.Config.Hostname, name)
+	}
+	if len(tracks) == 0 {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Nothing to export.")
+		return
+	}
+
+	if err := os.MkdirAll(playlist.FilesDirectory, 0700); err != nil {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Not Exported: "+err.Error())
+		return
+	}
+	if err := playlist.Export(path, tracks); err != nil {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Not Exported: "+err.Error())
+		return
+	}
+	helper.MsgDispatch(player.Client, isPrivate, sender, "Exported "+strconv.Itoa(len(tracks))+" track(s) to <b>"+html.EscapeString(path)+"</b>")
+}
@@ -0,0 +1,116 @@
+package commands
+
+import (
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/iotku/mumzic/database"
+	"github.com/iotku/mumzic/helper"
+	"github.com/iotku/mumzic/messages"
+	"github.com/iotku/mumzic/playback"
+)
+
+// savedPlaylist handles "!pl save|append|load|queue|delete <name>" and "!pl list".
+// save and append store the queue (from the current track on), load replaces the queue and queue adds to it.
+func savedPlaylist(player *playback.Player, sender string, isPrivate bool, arg string) {
+	args := strings.Fields(strings.ToLower(arg))
+	if len(args) == 0 || args[0] == "list" {
+		savedPlaylists(player, sender, isPrivate)
+		return
+	}
+	if len(args) != 2 {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Usage: pl [save/append/load/queue/delete] [name] or pl list")
+		return
+	}
+
+	hostname, name := player.Config.Hostname, args[1]
+	switch args[0] {
+	case "save", "append":
+		if !nameRegex.MatchString(name) || name == "list" {
+			helper.MsgDispatch(player.Client, isPrivate, sender, "Invalid playlist name: use letters, numbers, - and _")
+			return
+		}
+		owner, ok := userKey(player, sender)
+		if !ok {
+			helper.MsgDispatch(player.Client, isPrivate, sender, "Not Saved: you need a certificate or registration to own playlists")
+			return
+		}
+		if existing, err := database.GetPlaylist(hostname, name); err == nil && existing.Owner != owner && !isAdmin(player, sender) {
+			helper.MsgDispatch(player.Client, isPrivate, sender, "Not Saved: <b>"+name+"</b> belongs to "+html.EscapeString(existing.OwnerName))
+			return
+		}
+		tracks := player.Playlist.Upcoming()
+		if len(tracks) == 0 {
+			helper.MsgDispatch(player.Client, isPrivate, sender, "Not Saved: the queue is empty")
+			return
+		}
+		if err := database.SavePlaylist(hostname, name, owner, sender, tracks, args[0] == "append"); err != nil {
+			helper.MsgDispatch(player.Client, isPrivate, sender, "Not Saved: "+err.Error())
+			return
+		}
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Saved "+strconv.Itoa(len(tracks))+" track(s) to <b>"+name+"</b>")
+	case "load":
+		tracks, ok := loadSavedPlaylist(player, sender, isPrivate, name)
+		if !ok {
+			return
+		}
+		player.Stop(true)
+		duplicates := player.Playlist.Replace(tracks, sender)
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Loaded "+strconv.Itoa(len(tracks)-duplicates)+" track(s) from <b>"+name+"</b>"+
+			duplicatesNote(duplicates))
+		startQueued(player, sender, isPrivate, false)
+	case "queue", "add":
+		tracks, ok := loadSavedPlaylist(player, sender, isPrivate, name)
+		if !ok {
+			return
+		}
+		playNext := !player.Playlist.IsEmpty() && !player.Playlist.HasNext()
+		duplicates := player.Playlist.AddTracks(tracks, sender)
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Queued "+strconv.Itoa(len(tracks)-duplicates)+" track(s) from <b>"+name+"</b>"+
+			duplicatesNote(duplicates))
+		startQueued(player, sender, isPrivate, playNext)
+	case "delete", "del", "remove", "rm":
+		existing, err := database.GetPlaylist(hostname, name)
+		if err != nil {
+			helper.MsgDispatch(player.Client, isPrivate, sender, err.Error())
+			return
+		}
+		if owner, _ := userKey(player, sender); existing.Owner != owner && !isAdmin(player, sender) {
+			helper.MsgDispatch(player.Client, isPrivate, sender, "Not Removed: <b>"+name+"</b> belongs to "+html.EscapeString(existing.OwnerName))
+			return
+		}
+		database.RemovePlaylist(hostname, name)
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Removed playlist: <b>"+name+"</b>")
+	default:
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Usage: pl [save/append/load/queue/delete] [name] or pl list")
+	}
+}
+
+// loadSavedPlaylist returns the tracks of the playlist called name, telling sender if there are none
+func loadSavedPlaylist(player *playback.Player, sender string, isPrivate bool, name string) ([][]string, bool) {
+	if _, err := database.GetPlaylist(player.Config.Hostname, name); err != nil {
+		helper.MsgDispatch(player.Client, isPrivate, sender, err.Error())
+		return nil, false
+	}
+	tracks := database.GetPlaylistTracks(player.Config.Hostname, name)
+	if len(tracks) == 0 {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Playlist <b>"+name+"</b> is empty")
+		return nil, false
+	}
+	return tracks, true
+}
+
+func savedPlaylists(player *playback.Player, sender string, isPrivate bool) {
+	saved := database.GetPlaylists(player.Config.Hostname)
+	if len(saved) == 0 {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "No playlists saved, save the queue with <b>pl save [name]</b>")
+		return
+	}
+
+	output := messages.MakeTable("Saved Playlists", "Name", "Tracks", "Owner")
+	for _, v := range saved {
+		output.AddRow("<b>"+v.Name+"</b>", strconv.Itoa(v.Tracks), html.EscapeString(v.OwnerName))
+	}
+	helper.MsgDispatch(player.Client, isPrivate, sender, output.String())
+}
@@ -0,0 +1,91 @@
+package commands
+
+import (
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/iotku/mumzic/database"
+	"github.com/iotku/mumzic/helper"
+	"github.com/iotku/mumzic/messages"
+	"github.com/iotku/mumzic/playback"
+	"github.com/iotku/mumzic/youtubedl"
+)
+
+// Names of saved stations and playlists
+var nameRegex = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// station handles "!station add <name> <url>" and "!station del <name>", which only admins may use,
+// and "!station <name>" to play one
+func station(player *playback.Player, sender string, isPrivate bool, arg string) {
+	args := strings.Fields(arg)
+	if len(args) == 0 {
+		stations(player, sender, isPrivate)
+		return
+	}
+
+	hostname := player.Config.Hostname
+	subcommand := strings.ToLower(args[0])
+	if isStationSubcommand(subcommand) && subcommand != "list" && !isAdmin(player, sender) {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Only admins may change the saved stations.")
+		return
+	}
+	switch subcommand {
+	case "add", "save":
+		if len(args) != 3 {
+			helper.MsgDispatch(player.Client, isPrivate, sender, "Usage: station add [name] [URL]")
+			return
+		}
+		name, url := strings.ToLower(args[1]), helper.StripHTMLTags(args[2])
+		if !nameRegex.MatchString(name) || isStationSubcommand(name) {
+			helper.MsgDispatch(player.Client, isPrivate, sender, "Invalid station name: use letters, numbers, - and _")
+			return
+		}
+		if !strings.HasPrefix(url, "http") || !youtubedl.IsWhiteListedURL(url) {
+			helper.MsgDispatch(player.Client, isPrivate, sender, "Not Added: URL Doesn't meet whitelist")
+			return
+		}
+		if err := database.SaveStation(hostname, name, url); err != nil {
+			helper.MsgDispatch(player.Client, isPrivate, sender, "Not Added: "+err.Error())
+			return
+		}
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Saved station: <b>"+name+"</b>")
+	case "del", "delete", "remove", "rm":
+		if len(args) != 2 || !database.RemoveStation(hostname, strings.ToLower(args[1])) {
+			helper.MsgDispatch(player.Client, isPrivate, sender, "No such station.")
+			return
+		}
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Removed station: <b>"+strings.ToLower(args[1])+"</b>")
+	case "list":
+		stations(player, sender, isPrivate)
+	default:
+		url, err := database.GetStation(hostname, subcommand)
+		if err != nil {
+			helper.MsgDispatch(player.Client, isPrivate, sender, err.Error())
+			return
+		}
+		play(url, sender, isPrivate, player)
+	}
+}
+
+func isStationSubcommand(name string) bool {
+	switch name {
+	case "add", "save", "del", "delete", "remove", "rm", "list":
+		return true
+	}
+	return false
+}
+
+func stations(player *playback.Player, sender string, isPrivate bool) {
+	saved := database.GetStations(player.Config.Hostname)
+	if len(saved) == 0 {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "No stations saved, add one with <b>station add [name] [URL]</b>")
+		return
+	}
+
+	output := messages.MakeTable("Stations", "Name", "URL")
+	for _, v := range saved {
+		output.AddRow("<b>"+v.Name+"</b>", html.EscapeString(v.URL))
+	}
+	helper.MsgDispatch(player.Client, isPrivate, sender, output.String())
+}
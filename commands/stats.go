@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iotku/mumzic/database"
+	"github.com/iotku/mumzic/helper"
+	"github.com/iotku/mumzic/messages"
+	"github.com/iotku/mumzic/playback"
+)
+
+const topStats = 10
+
+var statsWindows = map[string]struct {
+	name   string
+	period time.Duration // 0 for all time
+}{
+	"day":  {"today", 24 * time.Hour},
+	"week": {"this week", 7 * 24 * time.Hour},
+	"all":  {"all time", 0},
+}
+
+var statsGroupings = map[string]struct{ by, title, column string }{
+	"tracks":     {database.StatsTracks, "Top Tracks", "Track"},
+	"artists":    {database.StatsArtists, "Top Artists", "Artist"},
+	"requesters": {database.StatsRequesters, "Top Requesters", "Requester"},
+}
+
+const statsUsage = "Usage: stats [tracks/artists/requesters] [day/week/all]"
+
+// parseStatsArgs splits "[grouping] [window]" in either order, the grouping is "" for the summary
+func parseStatsArgs(arg string) (grouping, window string, ok bool) {
+	window = "all"
+	args := strings.Fields(strings.ToLower(arg))
+	if len(args) > 2 {
+		return "", "", false
+	}
+	var sawWindow bool
+	for _, v := range args {
+		if _, isWindow := statsWindows[v]; isWindow && !sawWindow {
+			window, sawWindow = v, true
+		} else if _, isGrouping := statsGroupings[v]; isGrouping && grouping == "" {
+			grouping = v
+		} else {
+			return "", "", false
+		}
+	}
+	return grouping, window, true
+}
+
+// stats handles "!stats [tracks/artists/requesters] [day/week/all]", a summary if no grouping is given
+func stats(player *playback.Player, sender string, isPrivate bool, arg string) {
+	grouping, window, ok := parseStatsArgs(arg)
+	if !ok {
+		helper.MsgDispatch(player.Client, isPrivate, sender, statsUsage)
+		return
+	}
+	var since time.Time
+	if period := statsWindows[window].period; period != 0 {
+		since = time.Now().Add(-period)
+	}
+	hostname, windowName := player.Config.Hostname, statsWindows[window].name
+
+	if grouping != "" {
+		group := statsGroupings[grouping]
+		top := database.GetTopStats(hostname, group.by, since, topStats)
+		if len(top) == 0 {
+			helper.MsgDispatch(player.Client, isPrivate, sender, "Nothing has been played "+windowName+".")
+			return
+		}
+		output := messages.MakeTable(group.title+" ("+windowName+")", "#", group.column, "Plays", "Skip Rate", "Played")
+		for i, v := range top {
+			output.AddRow(strconv.Itoa(i+1), html.EscapeString(v.Name), strconv.Itoa(v.Plays), formatRate(v.SkipRate()),
+				messages.FormatDuration(v.Played))
+		}
+		helper.MsgDispatch(player.Client, isPrivate, sender, output.String())
+		return
+	}
+
+	totals := database.GetStatsTotals(hostname, since)
+	if totals.Plays == 0 {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Nothing has been played "+windowName+".")
+		return
+	}
+	output := messages.MakeTable("Stats (" + windowName + ")")
+	output.AddRow("Plays", strconv.Itoa(totals.Plays))
+	output.AddRow("Hours Played", fmt.Sprintf("%.1f", totals.Played.Hours()))
+	output.AddRow("Skip Rate", formatRate(totals.SkipRate()))
+	for _, name := range []string{"tracks", "artists", "requesters"} {
+		group := statsGroupings[name]
+		if top := database.GetTopStats(hostname, group.by, since, 1); len(top) != 0 {
+			output.AddRow("Top "+group.column, html.EscapeString(top[0].Name)+" ("+strconv.Itoa(top[0].Plays)+" plays)")
+		}
+	}
+	output.AddRow("---")
+	output.AddRow("Use <b>" + html.EscapeString(strings.TrimPrefix(statsUsage, "Usage: ")) + "</b> for more.")
+	helper.MsgDispatch(player.Client, isPrivate, sender, output.String())
+}
+
+func formatRate(rate float64) string {
+	return fmt.Sprintf("%.0f%%", rate*100)
+}
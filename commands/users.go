@@ -0,0 +1,23 @@
+package commands
+
+import (
+	"strconv"
+
+	"github.com/iotku/mumzic/playback"
+)
+
+// userKey identifies sender across name changes: registered users by their user ID, others by certificate hash.
+// ok is false if sender isn't connected or has neither.
+func userKey(player *playback.Player, sender string) (key string, ok bool) {
+	user := player.Client.Users.Find(sender)
+	if user == nil {
+		return "", false
+	}
+	if user.IsRegistered() {
+		return "id:" + strconv.FormatUint(uint64(user.UserID), 10), true
+	}
+	if user.Hash != "" {
+		return "hash:" + user.Hash, true
+	}
+	return "", false
+}
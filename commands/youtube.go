@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"context"
+	"html"
+	"strconv"
+	"sync"
+
+	"github.com/iotku/mumzic/helper"
+	"github.com/iotku/mumzic/messages"
+	"github.com/iotku/mumzic/playback"
+	"github.com/iotku/mumzic/youtubedl"
+)
+
+// How many results !yt asks YouTube for
+const youtubeSearchResults = 10
+
+// Last !yt results of each user, so !pick can refer to them by number
+var searchResults = struct {
+	sync.Mutex
+	bySender map[string][]youtubedl.SearchResult
+}{bySender: make(map[string][]youtubedl.SearchResult)}
+
+func youtubeSearch(player *playback.Player, sender string, isPrivate bool, arg string) {
+	if arg == "" {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Usage: yt [search terms]")
+		return
+	}
+
+	results, err := youtubedl.SearchYouTubeResults(context.Background(), arg, youtubeSearchResults)
+	if err != nil {
+		helper.MsgDispatch(player.Client, isPrivate, sender, err.Error())
+		return
+	}
+	searchResults.Lock()
+	searchResults.bySender[sender] = results
+	searchResults.Unlock()
+
+	rows := make([]string, 0, len(results))
+	for _, v := range results {
+		row := "<b>" + html.EscapeString(v.Title) + "</b> - " + html.EscapeString(v.Channel)
+		if v.Duration > 0 {
+			row += " (" + messages.FormatDuration(v.Duration) + ")"
+		}
+		rows = append(rows, row)
+	}
+
+	output := messages.MakeTable("YouTube Results")
+	messages.SaveMoreRows(sender, player.Config.MaxLines, rows, output)
+	output.AddRow("---")
+	output.AddRow("Use <b>pick [#]</b> to queue a result.")
+	helper.MsgDispatch(player.Client, isPrivate, sender, output.String())
+}
+
+// pick queues a result from the sender's last !yt search
+func pick(player *playback.Player, sender string, isPrivate bool, arg string) {
+	searchResults.Lock()
+	results := searchResults.bySender[sender]
+	searchResults.Unlock()
+	if len(results) == 0 {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Nothing to pick from, search with <b>yt [search terms]</b> first.")
+		return
+	}
+
+	index, err := strconv.Atoi(arg)
+	if err != nil || index < 0 || index >= len(results) {
+		helper.MsgDispatch(player.Client, isPrivate, sender, "Invalid pick: Valid range <b>[0-"+strconv.Itoa(len(results)-1)+"]</b>")
+		return
+	}
+	play(results[index].URL, sender, isPrivate, player)
+}
@@ -14,6 +14,16 @@ type Config struct {
 	Channel  string  // Channel the bot is occupying or last occupied
 	Hostname string  // Hostname of connected server
 	MaxLines int     // Most lines you want to output to the screen before more/less
+
+	Equalizer string // Equalizer preset applied to playback (see audio.Presets)
+	Filters   string // Space separated audio filters applied to playback (see audio.NewSettings)
+
+	MaxDuration int // Longest URL track (in minutes) which may be queued, 0 for no limit
+	CacheSize   int // Disk space (in MiB) for caching played URL tracks, 0 disables the cache
+
+	Segments string // Where sponsor/off-topic segments to skip come from (see segments.NewProvider), empty disables skipping
+
+	Duplicates string // What happens to tracks which are queued already: reject, warn or allow (the default when empty)
 }
 
 // Path to configuration db
@@ -21,21 +31,92 @@ var configDBPath = "./config.db"
 var ConfigDB *sql.DB
 
 func init() {
-	if _, err := os.Stat(configDBPath); os.IsNotExist(err) {
-		tx, _ := initConfigDB(configDBPath)
+	OpenConfigDB(configDBPath)
+}
+
+// OpenConfigDB makes the configuration database at path ConfigDB, creating it or adding missing tables and columns
+func OpenConfigDB(path string) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		tx, _ := initConfigDB(path)
 		checkErrPanic(tx.Commit())
 	}
 
-	ConfigDB = openDB(configDBPath)
+	ConfigDB = openDB(path)
+	createConfigTables()
 	migrateConfigDB()
 }
 
-// Old database schemas didn't have the MaxLines column, so add one.
+// Columns missing from older database schemas, added by migrateConfigDB
+var configColumns = []struct{ table, name, definition string }{
+	{"config", "MaxLines", "INTEGER DEFAULT 5"},
+	{"config", "Equalizer", "TEXT NOT NULL DEFAULT ''"},
+	{"config", "Filters", "TEXT NOT NULL DEFAULT ''"},
+	{"config", "MaxDuration", "INTEGER NOT NULL DEFAULT 0"},
+	{"config", "CacheSize", "INTEGER NOT NULL DEFAULT 0"},
+	{"config", "Segments", "TEXT NOT NULL DEFAULT ''"},
+	{"config", "Duplicates", "TEXT NOT NULL DEFAULT ''"},
+	{"history", "Artist", "TEXT NOT NULL DEFAULT ''"},
+}
+
+// Old database schemas didn't have newer columns (such as MaxLines), so add them.
 func migrateConfigDB() {
-	_, err := ConfigDB.Exec(`ALTER TABLE config ADD COLUMN MaxLines INTEGER DEFAULT 5`)
-	if err == nil {
-		log.Println("Config Migration: Added MaxLines column to config.")
-	} // if fails we assume the column already existed
+	for _, column := range configColumns {
+		_, err := ConfigDB.Exec(`ALTER TABLE ` + column.table + ` ADD COLUMN ` + column.name + ` ` + column.definition)
+		if err == nil {
+			log.Println("Config Migration: Added " + column.name + " column to " + column.table + ".")
+		} // if fails we assume the column already existed
+	}
+}
+
+// Tables holding per server data besides the config itself
+const configTablesDDL = `
+	CREATE TABLE IF NOT EXISTS "stations" (
+		"Hostname" TEXT NOT NULL,
+		"Name" TEXT NOT NULL,
+		"URL" TEXT NOT NULL,
+		PRIMARY KEY ("Hostname", "Name")
+	);
+	CREATE TABLE IF NOT EXISTS "playlists" (
+		"Hostname" TEXT NOT NULL,
+		"Name" TEXT NOT NULL,
+		"Owner" TEXT NOT NULL,
+		"OwnerName" TEXT NOT NULL,
+		"Updated" INTEGER NOT NULL,
+		PRIMARY KEY ("Hostname", "Name")
+	);
+	CREATE TABLE IF NOT EXISTS "playlist_tracks" (
+		"Hostname" TEXT NOT NULL,
+		"Name" TEXT NOT NULL,
+		"Position" INTEGER NOT NULL,
+		"Path" TEXT NOT NULL,
+		"Human" TEXT NOT NULL,
+		PRIMARY KEY ("Hostname", "Name", "Position")
+	);
+	CREATE TABLE IF NOT EXISTS "history" (
+		"ID" INTEGER PRIMARY KEY AUTOINCREMENT,
+		"Hostname" TEXT NOT NULL,
+		"Path" TEXT NOT NULL,
+		"Human" TEXT NOT NULL,
+		"Artist" TEXT NOT NULL DEFAULT '',
+		"Requester" TEXT NOT NULL,
+		"Started" INTEGER NOT NULL,
+		"Ended" INTEGER,
+		"Outcome" TEXT NOT NULL DEFAULT 'playing'
+	);
+	CREATE INDEX IF NOT EXISTS "history_hostname" ON "history" ("Hostname", "ID");
+	CREATE TABLE IF NOT EXISTS "likes" (
+		"Hostname" TEXT NOT NULL,
+		"Owner" TEXT NOT NULL,
+		"Path" TEXT NOT NULL,
+		"Human" TEXT NOT NULL,
+		"Added" INTEGER NOT NULL,
+		PRIMARY KEY ("Hostname", "Owner", "Path")
+	);
+`
+
+func createConfigTables() {
+	_, err := ConfigDB.Exec(configTablesDDL)
+	checkErrPanic(err)
 }
 
 func NewConfig(hostname string) *Config {
@@ -48,8 +129,9 @@ func NewConfig(hostname string) *Config {
 	}
 
 	var config Config
-	row := ConfigDB.QueryRow("SELECT Hostname, VolumeLevel, LastChannel, CmdPrefix, SongDB, Maxlines FROM config WHERE Hostname = ?", hostname)
-	err := row.Scan(&config.Hostname, &config.Volume, &config.Channel, &config.Prefix, &MediaDBPath, &config.MaxLines)
+	row := ConfigDB.QueryRow("SELECT Hostname, VolumeLevel, LastChannel, CmdPrefix, SongDB, Maxlines, Equalizer, Filters, MaxDuration, CacheSize, Segments, Duplicates FROM config WHERE Hostname = ?", hostname)
+	err := row.Scan(&config.Hostname, &config.Volume, &config.Channel, &config.Prefix, &MediaDBPath, &config.MaxLines,
+		&config.Equalizer, &config.Filters, &config.MaxDuration, &config.CacheSize, &config.Segments, &config.Duplicates)
 	if err != nil && err == sql.ErrNoRows { // create new configuration
 		tx, _ := ConfigDB.Begin()
 		writeConfigToDB(defaultConfig, prepareStatementInsert(tx))
@@ -74,13 +156,14 @@ func (config *Config) Save() {
 		checkErrPanic(stmt.Close())
 	}()
 	// There must be a better way to do this, but I'm tired and this will do for now.
-	_, err = stmt.Exec(config.Volume, config.Channel, config.Prefix, config.MaxLines, config.Hostname)
+	_, err = stmt.Exec(config.Volume, config.Channel, config.Prefix, config.MaxLines, config.Equalizer, config.Filters,
+		config.MaxDuration, config.CacheSize, config.Segments, config.Duplicates, config.Hostname)
 	checkErrPanic(err)
 	checkErrPanic(tx.Commit())
 }
 
 func prepareUpdate(tx *sql.Tx) *sql.Stmt {
-	stmt, err := tx.Prepare(`UPDATE config SET VolumeLevel = ?, LastChannel = ?, CmdPrefix = ?, MaxLines = ? WHERE Hostname = ?;`)
+	stmt, err := tx.Prepare(`UPDATE config SET VolumeLevel = ?, LastChannel = ?, CmdPrefix = ?, MaxLines = ?, Equalizer = ?, Filters = ?, MaxDuration = ?, CacheSize = ?, Segments = ?, Duplicates = ? WHERE Hostname = ?;`)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -88,7 +171,7 @@ func prepareUpdate(tx *sql.Tx) *sql.Stmt {
 }
 
 func prepareStatementInsert(tx *sql.Tx) *sql.Stmt {
-	stmt, err := tx.Prepare(`INSERT INTO "config" (Hostname, VolumeLevel, LastChannel, CmdPrefix, SongDB, MaxLines) VALUES (?, ?, ?, ?, ?, ?);`)
+	stmt, err := tx.Prepare(`INSERT INTO "config" (Hostname, VolumeLevel, LastChannel, CmdPrefix, SongDB, MaxLines, Equalizer, Filters, MaxDuration, CacheSize, Segments, Duplicates) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -96,7 +179,8 @@ func prepareStatementInsert(tx *sql.Tx) *sql.Stmt {
 }
 
 func writeConfigToDB(config Config, stmt *sql.Stmt) {
-	_, err := stmt.Exec(config.Hostname, config.Volume, config.Channel, config.Prefix, MediaDBPath, config.MaxLines)
+	_, err := stmt.Exec(config.Hostname, config.Volume, config.Channel, config.Prefix, MediaDBPath, config.MaxLines,
+		config.Equalizer, config.Filters, config.MaxDuration, config.CacheSize, config.Segments, config.Duplicates)
 	if err != nil {
 		log.Fatalln("Writing Config Failed!:", err.Error())
 	}
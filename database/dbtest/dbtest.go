@@ -0,0 +1,65 @@
+// Package dbtest sets up temporary databases for tests of packages using package database
+package dbtest
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/iotku/mumzic/database"
+)
+
+// Track is a row of the music table of a media.db
+type Track struct {
+	Artist, Album, Title, Path string
+	Genre, Year                string
+}
+
+// UseConfigDB makes an empty configuration database database.ConfigDB for the rest of the test
+func UseConfigDB(t testing.TB) {
+	t.Helper()
+	old := database.ConfigDB
+	database.OpenConfigDB(filepath.Join(t.TempDir(), "config.db"))
+	db := database.ConfigDB
+	t.Cleanup(func() {
+		db.Close()
+		database.ConfigDB = old
+	})
+}
+
+// UseMediaDB makes a media.db holding tracks database.MediaDB for the rest of the test.
+// Without extended, its music table lacks the genre and year columns like that of older generators.
+func UseMediaDB(t testing.TB, tracks []Track, extended bool) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "media.db")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema := "CREATE TABLE music (artist TEXT, album TEXT, title TEXT, path TEXT"
+	insert := "INSERT INTO music VALUES (?, ?, ?, ?"
+	if extended {
+		schema += ", genre TEXT, year TEXT"
+		insert += ", ?, ?"
+	}
+	if _, err := db.Exec(schema + ")"); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range tracks {
+		args := []interface{}{v.Artist, v.Album, v.Title, v.Path}
+		if extended {
+			args = append(args, v.Genre, v.Year)
+		}
+		if _, err := db.Exec(insert+")", args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	oldDB, oldPath := database.MediaDB, database.MediaDBPath
+	database.MediaDB, database.MediaDBPath = db, path
+	t.Cleanup(func() {
+		db.Close()
+		database.MediaDB, database.MediaDBPath = oldDB, oldPath
+	})
+}
@@ -0,0 +1,100 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Outcomes of history entries
+const (
+	OutcomePlaying   = "playing" // Still playing, or the bot stopped before the track ended
+	OutcomeCompleted = "completed"
+	OutcomeSkipped   = "skipped"
+)
+
+// HistoryEntry is a track played on a server
+type HistoryEntry struct {
+	ID        int64
+	Path      string
+	Human     string
+	Artist    string // Tagged artist or uploader, "" if unknown
+	Requester string // Name of the user who queued the track, "" for radio mode
+	Started   time.Time
+	Ended     time.Time // Zero while playing
+	Outcome   string
+}
+
+// StartHistory records that a track started playing, returning the ID for FinishHistory
+func StartHistory(hostname, path, human, artist, requester string) int64 {
+	result, err := ConfigDB.Exec(`INSERT INTO history (Hostname, Path, Human, Artist, Requester, Started) VALUES (?, ?, ?, ?, ?, ?)`,
+		hostname, path, human, artist, requester, time.Now().Unix())
+	checkErrPanic(err)
+	id, err := result.LastInsertId()
+	checkErrPanic(err)
+	return id
+}
+
+// FinishHistory records how the track of history entry id ended
+func FinishHistory(id int64, outcome string) {
+	_, err := ConfigDB.Exec(`UPDATE history SET Ended = ?, Outcome = ? WHERE ID = ?`, time.Now().Unix(), outcome, id)
+	checkErrPanic(err)
+}
+
+// GetHistory returns the last amount tracks played on hostname, most recent first
+func GetHistory(hostname string, amount int) []HistoryEntry {
+	rows, err := ConfigDB.Query(`SELECT ID, Path, Human, Artist, Requester, Started, Ended, Outcome FROM history
+		WHERE Hostname = ? ORDER BY ID DESC LIMIT ?`, hostname, amount)
+	checkErrPanic(err)
+	defer rows.Close()
+
+	var history []HistoryEntry
+	for rows.Next() {
+		var entry HistoryEntry
+		var started int64
+		var ended sql.NullInt64
+		checkErrPanic(rows.Scan(&entry.ID, &entry.Path, &entry.Human, &entry.Artist, &entry.Requester, &started, &ended, &entry.Outcome))
+		entry.Started = time.Unix(started, 0)
+		if ended.Valid {
+			entry.Ended = time.Unix(ended.Int64, 0)
+		}
+		history = append(history, entry)
+	}
+	checkErrPanic(rows.Err())
+	return history
+}
+
+// GetRecentPaths returns the paths of the last amount tracks played on hostname
+func GetRecentPaths(hostname string, amount int) []string {
+	rows, err := ConfigDB.Query(`SELECT Path FROM history WHERE Hostname = ? ORDER BY ID DESC LIMIT ?`, hostname, amount)
+	checkErrPanic(err)
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var path string
+		checkErrPanic(rows.Scan(&path))
+		paths = append(paths, path)
+	}
+	checkErrPanic(rows.Err())
+	return paths
+}
+
+// GetCoPlayed returns the tracks played on hostname within window tracks of the track at path, by how often they were,
+// up to amount of them
+func GetCoPlayed(hostname, path string, window, amount int) map[string]int {
+	rows, err := ConfigDB.Query(`SELECT h2.Path, COUNT(*) FROM history h1
+		JOIN history h2 ON h2.Hostname = h1.Hostname AND h2.ID BETWEEN h1.ID - ? AND h1.ID + ? AND h2.Path != h1.Path
+		WHERE h1.Hostname = ? AND h1.Path = ? GROUP BY h2.Path ORDER BY COUNT(*) DESC LIMIT ?`, window, window, hostname, path, amount)
+	checkErrPanic(err)
+	defer rows.Close()
+
+	coPlayed := make(map[string]int)
+	for rows.Next() {
+		var coPath string
+		var count int
+		checkErrPanic(rows.Scan(&coPath, &count))
+		coPlayed[coPath] = count
+	}
+	checkErrPanic(rows.Err())
+	return coPlayed
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"strings"
+	"time"
+)
+
+// AddLike stores that owner (see commands.userKey) likes the track at path, returning false if they did already
+func AddLike(hostname, owner, path, human string) bool {
+	result, err := ConfigDB.Exec(`INSERT INTO likes (Hostname, Owner, Path, Human, Added) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT DO NOTHING`, hostname, owner, path, human, time.Now().Unix())
+	checkErrPanic(err)
+	affected, err := result.RowsAffected()
+	checkErrPanic(err)
+	return affected > 0
+}
+
+// RemoveLike forgets that owner likes the track at path, returning false if they didn't
+func RemoveLike(hostname, owner, path string) bool {
+	result, err := ConfigDB.Exec(`DELETE FROM likes WHERE Hostname = ? AND Owner = ? AND Path = ?`, hostname, owner, path)
+	checkErrPanic(err)
+	affected, err := result.RowsAffected()
+	checkErrPanic(err)
+	return affected > 0
+}
+
+// GetLikes returns the tracks owner likes as path and human title pairs, in the order they were liked or shuffled
+func GetLikes(hostname, owner string, shuffle bool) [][]string {
+	order := "Added, rowid"
+	if shuffle {
+		order = "random()"
+	}
+	rows, err := ConfigDB.Query(`SELECT Path, Human FROM likes WHERE Hostname = ? AND Owner = ? ORDER BY `+order, hostname, owner)
+	checkErrPanic(err)
+	defer rows.Close()
+
+	var tracks [][]string
+	for rows.Next() {
+		var path, human string
+		checkErrPanic(rows.Scan(&path, &human))
+		tracks = append(tracks, []string{path, human})
+	}
+	checkErrPanic(rows.Err())
+	return tracks
+}
+
+// GetLikeCounts returns how many users like each of the tracks at paths, tracks nobody likes are left out
+func GetLikeCounts(hostname string, paths []string) map[string]int {
+	counts := make(map[string]int)
+	if len(paths) == 0 {
+		return counts
+	}
+	args := []interface{}{hostname}
+	for _, v := range paths {
+		args = append(args, v)
+	}
+	rows, err := ConfigDB.Query(`SELECT Path, COUNT(*) FROM likes WHERE Hostname = ? AND Path IN (?`+
+		strings.Repeat(", ?", len(paths)-1)+`) GROUP BY Path`, args...)
+	checkErrPanic(err)
+	defer rows.Close()
+
+	for rows.Next() {
+		var path string
+		var count int
+		checkErrPanic(rows.Scan(&path, &count))
+		counts[path] = count
+	}
+	checkErrPanic(rows.Err())
+	return counts
+}
@@ -0,0 +1,119 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// SavedPlaylist is a named list of tracks saved by a user of a server
+type SavedPlaylist struct {
+	Name      string
+	Owner     string // Identity of the user who created it (see commands.userKey)
+	OwnerName string // Display name of the owner when it was created
+	Tracks    int
+	Updated   time.Time
+}
+
+// SavePlaylist stores tracks (path and human title pairs, like playlist.List) as the playlist called name.
+// An existing playlist is replaced, or extended if appendTracks is set, keeping its owner.
+func SavePlaylist(hostname, name, owner, ownerName string, tracks [][]string, appendTracks bool) error {
+	tx, err := ConfigDB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //#nosec G104 -- no-op after Commit
+
+	_, err = tx.Exec(`INSERT INTO playlists (Hostname, Name, Owner, OwnerName, Updated) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (Hostname, Name) DO UPDATE SET Updated = excluded.Updated`, hostname, name, owner, ownerName, time.Now().Unix())
+	if err != nil {
+		return err
+	}
+
+	var position int
+	if appendTracks {
+		err = tx.QueryRow(`SELECT COALESCE(MAX(Position) + 1, 0) FROM playlist_tracks WHERE Hostname = ? AND Name = ?`,
+			hostname, name).Scan(&position)
+	} else {
+		_, err = tx.Exec(`DELETE FROM playlist_tracks WHERE Hostname = ? AND Name = ?`, hostname, name)
+	}
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO playlist_tracks (Hostname, Name, Position, Path, Human) VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for i, track := range tracks {
+		if _, err := stmt.Exec(hostname, name, position+i, track[0], track[1]); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetPlaylist returns the playlist called name, or an error if there is no such playlist
+func GetPlaylist(hostname, name string) (SavedPlaylist, error) {
+	playlist := SavedPlaylist{Name: name}
+	var updated int64
+	err := ConfigDB.QueryRow(`SELECT Owner, OwnerName, Updated,
+		(SELECT COUNT(*) FROM playlist_tracks t WHERE t.Hostname = p.Hostname AND t.Name = p.Name)
+		FROM playlists p WHERE Hostname = ? AND Name = ?`, hostname, name).Scan(&playlist.Owner, &playlist.OwnerName, &updated, &playlist.Tracks)
+	if errors.Is(err, sql.ErrNoRows) {
+		return playlist, errors.New("no playlist called " + name)
+	}
+	playlist.Updated = time.Unix(updated, 0)
+	return playlist, err
+}
+
+// GetPlaylistTracks returns the tracks of the playlist called name in order as path and human title pairs
+func GetPlaylistTracks(hostname, name string) [][]string {
+	rows, err := ConfigDB.Query(`SELECT Path, Human FROM playlist_tracks WHERE Hostname = ? AND Name = ? ORDER BY Position`, hostname, name)
+	checkErrPanic(err)
+	defer rows.Close()
+
+	var tracks [][]string
+	for rows.Next() {
+		var path, human string
+		checkErrPanic(rows.Scan(&path, &human))
+		tracks = append(tracks, []string{path, human})
+	}
+	checkErrPanic(rows.Err())
+	return tracks
+}
+
+// GetPlaylists returns all saved playlists for hostname sorted by name
+func GetPlaylists(hostname string) []SavedPlaylist {
+	rows, err := ConfigDB.Query(`SELECT Name, Owner, OwnerName, Updated,
+		(SELECT COUNT(*) FROM playlist_tracks t WHERE t.Hostname = p.Hostname AND t.Name = p.Name)
+		FROM playlists p WHERE Hostname = ? ORDER BY Name`, hostname)
+	checkErrPanic(err)
+	defer rows.Close()
+
+	var playlists []SavedPlaylist
+	for rows.Next() {
+		var playlist SavedPlaylist
+		var updated int64
+		checkErrPanic(rows.Scan(&playlist.Name, &playlist.Owner, &playlist.OwnerName, &updated, &playlist.Tracks))
+		playlist.Updated = time.Unix(updated, 0)
+		playlists = append(playlists, playlist)
+	}
+	checkErrPanic(rows.Err())
+	return playlists
+}
+
+// RemovePlaylist deletes the playlist called name and its tracks, returning false if it didn't exist
+func RemovePlaylist(hostname, name string) bool {
+	tx, err := ConfigDB.Begin()
+	checkErrPanic(err)
+	_, err = tx.Exec(`DELETE FROM playlist_tracks WHERE Hostname = ? AND Name = ?`, hostname, name)
+	checkErrPanic(err)
+	result, err := tx.Exec(`DELETE FROM playlists WHERE Hostname = ? AND Name = ?`, hostname, name)
+	checkErrPanic(err)
+	checkErrPanic(tx.Commit())
+	affected, err := result.RowsAffected()
+	checkErrPanic(err)
+	return affected > 0
+}
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// Station is a radio or stream URL saved under a name for a server
+type Station struct {
+	Name string
+	URL  string
+}
+
+// SaveStation adds or replaces the station called name for hostname
+func SaveStation(hostname, name, url string) error {
+	_, err := ConfigDB.Exec(`INSERT OR REPLACE INTO stations (Hostname, Name, URL) VALUES (?, ?, ?)`, hostname, name, url)
+	return err
+}
+
+// GetStation returns the URL of the station called name, or an error if there is no such station
+func GetStation(hostname, name string) (string, error) {
+	var url string
+	err := ConfigDB.QueryRow(`SELECT URL FROM stations WHERE Hostname = ? AND Name = ?`, hostname, name).Scan(&url)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", errors.New("no station called " + name)
+	}
+	return url, err
+}
+
+// GetStations returns all saved stations for hostname sorted by name
+func GetStations(hostname string) []Station {
+	rows, err := ConfigDB.Query(`SELECT Name, URL FROM stations WHERE Hostname = ? ORDER BY Name`, hostname)
+	checkErrPanic(err)
+	defer rows.Close()
+
+	var stations []Station
+	for rows.Next() {
+		var station Station
+		checkErrPanic(rows.Scan(&station.Name, &station.URL))
+		stations = append(stations, station)
+	}
+	checkErrPanic(rows.Err())
+	return stations
+}
+
+// RemoveStation deletes the station called name, returning false if it didn't exist
+func RemoveStation(hostname, name string) bool {
+	result, err := ConfigDB.Exec(`DELETE FROM stations WHERE Hostname = ? AND Name = ?`, hostname, name)
+	checkErrPanic(err)
+	affected, err := result.RowsAffected()
+	checkErrPanic(err)
+	return affected > 0
+}
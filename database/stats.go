@@ -0,0 +1,97 @@
+package database
+
+import (
+	"strings"
+	"time"
+)
+
+// Groupings of play statistics, see GetTopStats
+const (
+	StatsTracks     = "Path"
+	StatsArtists    = "Artist"
+	StatsRequesters = "Requester"
+)
+
+// PlayStats sums up history entries
+type PlayStats struct {
+	Name   string        // Track, artist or requester, empty for totals
+	Plays  int           // Times started
+	Skips  int           // Times skipped before the end
+	Played time.Duration // Time spent playing, not counting tracks still playing
+}
+
+// SkipRate returns the share of plays which were skipped, from 0 to 1
+func (stats PlayStats) SkipRate() float64 {
+	if stats.Plays == 0 {
+		return 0
+	}
+	return float64(stats.Skips) / float64(stats.Plays)
+}
+
+// GetStatsTotals sums up the history of hostname since the given time
+func GetStatsTotals(hostname string, since time.Time) PlayStats {
+	var stats PlayStats
+	var played int64
+	err := ConfigDB.QueryRow(`SELECT COUNT(*), COALESCE(SUM(Outcome = ?), 0), COALESCE(SUM(Ended - Started), 0)
+		FROM history WHERE Hostname = ? AND Started >= ?`, OutcomeSkipped, hostname, since.Unix()).Scan(&stats.Plays, &stats.Skips, &played)
+	checkErrPanic(err)
+	stats.Played = time.Duration(played) * time.Second
+	return stats
+}
+
+// GetTopStats returns the amount most played tracks, artists or requesters (by is one of the Stats constants) of
+// hostname since the given time. Tracks are named by their human title, radio plays and unknown artists are left out.
+func GetTopStats(hostname, by string, since time.Time, amount int) []PlayStats {
+	name := by
+	switch by {
+	case StatsTracks:
+		name = "MAX(Human)"
+	case StatsArtists, StatsRequesters:
+	default:
+		panic("database: unknown stats grouping " + by)
+	}
+
+	rows, err := ConfigDB.Query(`SELECT `+name+`, COUNT(*), COALESCE(SUM(Outcome = ?), 0), COALESCE(SUM(Ended - Started), 0)
+		FROM history WHERE Hostname = ? AND Started >= ? AND `+by+` != ''
+		GROUP BY `+by+` ORDER BY COUNT(*) DESC, MAX(ID) DESC LIMIT ?`, OutcomeSkipped, hostname, since.Unix(), amount)
+	checkErrPanic(err)
+	defer rows.Close()
+
+	var top []PlayStats
+	for rows.Next() {
+		var stats PlayStats
+		var played int64
+		checkErrPanic(rows.Scan(&stats.Name, &stats.Plays, &stats.Skips, &played))
+		stats.Played = time.Duration(played) * time.Second
+		top = append(top, stats)
+	}
+	checkErrPanic(rows.Err())
+	return top
+}
+
+// GetPathStats returns how often each of the tracks at paths was played on hostname, tracks never played are left out
+func GetPathStats(hostname string, paths []string) map[string]PlayStats {
+	stats := make(map[string]PlayStats)
+	if len(paths) == 0 {
+		return stats
+	}
+	args := []interface{}{OutcomeSkipped, hostname}
+	for _, v := range paths {
+		args = append(args, v)
+	}
+	rows, err := ConfigDB.Query(`SELECT Path, COUNT(*), COALESCE(SUM(Outcome = ?), 0), COALESCE(SUM(Ended - Started), 0)
+		FROM history WHERE Hostname = ? AND Path IN (?`+strings.Repeat(", ?", len(paths)-1)+`) GROUP BY Path`, args...)
+	checkErrPanic(err)
+	defer rows.Close()
+
+	for rows.Next() {
+		var path string
+		var pathStats PlayStats
+		var played int64
+		checkErrPanic(rows.Scan(&path, &pathStats.Plays, &pathStats.Skips, &played))
+		pathStats.Played = time.Duration(played) * time.Second
+		stats[path] = pathStats
+	}
+	checkErrPanic(rows.Err())
+	return stats
+}
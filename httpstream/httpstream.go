@@ -0,0 +1,390 @@
+package httpstream
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iotku/mumzic/youtubedl"
+)
+
+// ErrNotStream is returned by Open when the URL doesn't serve audio (e.g. a video page for ytdl)
+var ErrNotStream = errors.New("not an audio stream")
+
+// ErrNotWhitelisted is returned by Open when a station file entry or redirect leads to a URL not in the whitelist
+var ErrNotWhitelisted = errors.New("URL Doesn't Meet whitelist")
+
+// Most redirects followed for a single request
+const maxRedirects = 10
+
+// Most station files redirecting to another station file we follow
+const maxPlaylistDepth = 3
+
+// Largest station file we are willing to parse
+const maxPlaylistSize = 64 * 1024
+
+var client = &http.Client{
+	// No overall Timeout as it would also limit how long we can read the stream body
+	Transport: &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           (&net.Dialer{Timeout: 10 * time.Second}).DialContext,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 10 * time.Second,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return errors.New("stopped after " + strconv.Itoa(maxRedirects) + " redirects")
+		}
+		if !youtubedl.IsWhiteListedURL(req.URL.String()) {
+			return ErrNotWhitelisted
+		}
+		return nil
+	},
+}
+
+// How long and how many URLs which turned out not to be streams are remembered
+const (
+	notStreamTTL  = time.Hour
+	notStreamSize = 512
+)
+
+// URLs which turned out not to be streams (and when), so we don't probe them again on every play
+var notStreams = struct {
+	sync.Mutex
+	urls map[string]time.Time
+}{urls: make(map[string]time.Time)}
+
+// Stream is an opened HTTP audio stream. Reading returns the audio with any ICY metadata removed.
+type Stream struct {
+	URL  string // Stream URL after following station files
+	Name string // Station name from the icy-name header, or the file name
+	Live bool   // Stream has no fixed length (e.g. an Icecast station)
+
+	// OnTitle is called from Read whenever the station announces a new StreamTitle
+	OnTitle func(title string)
+
+	body      io.ReadCloser
+	metaInt   int // Audio bytes between ICY metadata blocks, 0 if the server doesn't send any
+	remaining int // Audio bytes left until the next metadata block
+	title     string
+}
+
+// Open connects to rawURL, following M3U and PLS station files to the actual audio.
+// ErrNotStream is returned if the URL serves something else. Every URL along the way has to be whitelisted.
+func Open(rawURL string) (*Stream, error) {
+	if knownNotStream(rawURL) {
+		return nil, ErrNotStream
+	}
+
+	stream, err := open(rawURL, 0)
+	if errors.Is(err, ErrNotStream) {
+		rememberNotStream(rawURL)
+	}
+	return stream, err
+}
+
+// Probe returns what Open would, without reading any audio. The returned Stream has nothing to Read.
+// A HEAD request is enough for most URLs, station files and servers refusing HEAD fall back to Open.
+func Probe(rawURL string) (*Stream, error) {
+	if knownNotStream(rawURL) {
+		return nil, ErrNotStream
+	}
+
+	stream, err := probe(rawURL)
+	if errors.Is(err, ErrNotStream) {
+		rememberNotStream(rawURL)
+	}
+	return stream, err
+}
+
+// IsStream returns whether rawURL is an audio stream (with its name), rather than something for ytdl
+func IsStream(rawURL string) (name string, ok bool) {
+	stream, err := Probe(rawURL)
+	if err != nil {
+		return "", false
+	}
+	return stream.Name, true
+}
+
+// knownNotStream returns whether rawURL recently turned out not to be a stream
+func knownNotStream(rawURL string) bool {
+	notStreams.Lock()
+	defer notStreams.Unlock()
+	checked, ok := notStreams.urls[rawURL]
+	return ok && time.Since(checked) < notStreamTTL
+}
+
+// rememberNotStream records that rawURL is not a stream, evicting the oldest URL when full
+func rememberNotStream(rawURL string) {
+	notStreams.Lock()
+	defer notStreams.Unlock()
+	if _, ok := notStreams.urls[rawURL]; !ok && len(notStreams.urls) >= notStreamSize {
+		var oldest string
+		for key, checked := range notStreams.urls {
+			if oldest == "" || checked.Before(notStreams.urls[oldest]) {
+				oldest = key
+			}
+		}
+		delete(notStreams.urls, oldest)
+	}
+	notStreams.urls[rawURL] = time.Now()
+}
+
+func probe(rawURL string) (*Stream, error) {
+	if !youtubedl.IsWhiteListedURL(rawURL) {
+		return nil, ErrNotWhitelisted
+	}
+
+	req, err := http.NewRequest(http.MethodHead, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Icy-MetaData", "1")
+	resp, err := client.Do(req)
+	if errors.Is(err, ErrNotWhitelisted) {
+		return nil, err
+	}
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			switch contentKind(resp) {
+			case "audio":
+				stream := newStream(resp)
+				stream.body = http.NoBody
+				return stream, nil
+			case "":
+				return nil, ErrNotStream
+			}
+		}
+	}
+
+	// Station file, or the server doesn't answer HEAD properly
+	stream, err := open(rawURL, 0)
+	if err != nil {
+		return nil, err
+	}
+	stream.Close() //#nosec G104 -- nothing was read
+	stream.body = http.NoBody
+	return stream, nil
+}
+
+func open(rawURL string, depth int) (*Stream, error) {
+	if depth > maxPlaylistDepth {
+		return nil, errors.New("too many nested station files")
+	}
+	if !youtubedl.IsWhiteListedURL(rawURL) {
+		return nil, ErrNotWhitelisted
+	}
+
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Icy-MetaData", "1")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, errors.New("stream responded with status " + strconv.Itoa(resp.StatusCode))
+	}
+
+	switch kind := contentKind(resp); kind {
+	case "m3u", "pls":
+		defer resp.Body.Close()
+		data, err := io.ReadAll(io.LimitReader(resp.Body, maxPlaylistSize))
+		if err != nil {
+			return nil, err
+		}
+		var entry string
+		if kind == "m3u" {
+			entry = parseM3U(data)
+		} else {
+			entry = parsePLS(data)
+		}
+		if entry == "" {
+			return nil, ErrNotStream
+		}
+		if entry, err = resolveEntry(resp.Request.URL, entry); err != nil {
+			return nil, err
+		}
+		return open(entry, depth+1)
+	case "audio":
+		return newStream(resp), nil
+	default:
+		resp.Body.Close()
+		return nil, ErrNotStream
+	}
+}
+
+// contentKind classifies a response as a "m3u" or "pls" station file, "audio" or "" for anything else
+func contentKind(resp *http.Response) string {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch mediaType {
+	case "audio/x-mpegurl", "audio/mpegurl", "application/x-mpegurl":
+		return "m3u"
+	case "audio/x-scpls", "application/pls+xml":
+		return "pls"
+	case "application/ogg":
+		return "audio"
+	case "", "application/octet-stream", "text/plain":
+		// Generic types, go by the file extension
+	default:
+		if strings.HasPrefix(mediaType, "audio/") {
+			return "audio"
+		}
+		return ""
+	}
+
+	if resp.Header.Get("icy-metaint") != "" || resp.Header.Get("icy-name") != "" {
+		return "audio"
+	}
+	switch strings.ToLower(path.Ext(resp.Request.URL.Path)) {
+	case ".m3u":
+		return "m3u"
+	case ".pls":
+		return "pls"
+	case ".mp3", ".ogg", ".oga", ".opus", ".aac", ".m4a", ".flac", ".wav":
+		return "audio"
+	}
+	return ""
+}
+
+// parseM3U returns the first entry of an M3U station file, HLS playlists are left to ytdl
+func parseM3U(data []byte) string {
+	if bytes.Contains(data, []byte("#EXT-X-")) {
+		return ""
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return line
+		}
+	}
+	return ""
+}
+
+// parsePLS returns the first FileN entry of a PLS station file
+func parsePLS(data []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if ok && strings.HasPrefix(strings.ToLower(key), "file") {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// resolveEntry resolves a station file entry relative to the station file, only allowing http(s) URLs
+func resolveEntry(base *url.URL, entry string) (string, error) {
+	ref, err := url.Parse(entry)
+	if err != nil {
+		return "", err
+	}
+	resolved := base.ResolveReference(ref)
+	if resolved.Scheme != "http" && resolved.Scheme != "https" {
+		return "", errors.New("station file entry is not a http(s) URL")
+	}
+	return resolved.String(), nil
+}
+
+func newStream(resp *http.Response) *Stream {
+	stream := &Stream{
+		URL:  resp.Request.URL.String(),
+		Name: strings.TrimSpace(resp.Header.Get("icy-name")),
+		Live: resp.ContentLength < 0 || resp.Header.Get("icy-metaint") != "",
+		body: resp.Body,
+	}
+	if stream.Name == "" {
+		stream.Name = path.Base(resp.Request.URL.Path)
+		if stream.Name == "/" || stream.Name == "." {
+			stream.Name = resp.Request.URL.Host
+		}
+	}
+	if metaInt, err := strconv.Atoi(resp.Header.Get("icy-metaint")); err == nil && metaInt > 0 {
+		stream.metaInt = metaInt
+		stream.remaining = metaInt
+	}
+	return stream
+}
+
+// Title returns the last StreamTitle announced by the station
+func (stream *Stream) Title() string {
+	return stream.title
+}
+
+func (stream *Stream) Read(p []byte) (int, error) {
+	if stream.metaInt == 0 {
+		return stream.body.Read(p)
+	}
+
+	if stream.remaining == 0 {
+		if err := stream.readMetadata(); err != nil {
+			return 0, err
+		}
+		stream.remaining = stream.metaInt
+	}
+	if len(p) > stream.remaining {
+		p = p[:stream.remaining]
+	}
+	n, err := stream.body.Read(p)
+	stream.remaining -= n
+	return n, err
+}
+
+// readMetadata consumes an ICY metadata block: a length byte (in 16 byte units) followed by
+// text such as StreamTitle='Artist - Title';
+func (stream *Stream) readMetadata() error {
+	var length [1]byte
+	if _, err := io.ReadFull(stream.body, length[:]); err != nil {
+		return err
+	}
+	if length[0] == 0 {
+		return nil
+	}
+
+	metadata := make([]byte, int(length[0])*16)
+	if _, err := io.ReadFull(stream.body, metadata); err != nil {
+		return err
+	}
+	title, ok := parseStreamTitle(string(bytes.TrimRight(metadata, "\x00")))
+	if ok && title != stream.title {
+		stream.title = title
+		if stream.OnTitle != nil {
+			stream.OnTitle(title)
+		}
+	}
+	return nil
+}
+
+func parseStreamTitle(metadata string) (string, bool) {
+	const key = "StreamTitle='"
+	start := strings.Index(metadata, key)
+	if start < 0 {
+		return "", false
+	}
+	value := metadata[start+len(key):]
+	if end := strings.Index(value, "';"); end >= 0 {
+		value = value[:end]
+	} else {
+		value = strings.TrimSuffix(value, "'")
+	}
+	return strings.TrimSpace(value), true
+}
+
+func (stream *Stream) Close() error {
+	return stream.body.Close()
+}
@@ -0,0 +1,251 @@
+package httpstream
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/iotku/mumzic/youtubedl"
+)
+
+// icyBody interleaves audio with ICY metadata blocks every metaInt bytes
+func icyBody(metaInt int, audio []byte, titles ...string) []byte {
+	var body bytes.Buffer
+	for i, title := range titles {
+		body.Write(audio[i*metaInt : (i+1)*metaInt])
+		meta := "StreamTitle='" + title + "';"
+		blocks := (len(meta) + 15) / 16
+		body.WriteByte(byte(blocks))
+		body.WriteString(meta + strings.Repeat("\x00", blocks*16-len(meta)))
+	}
+	body.Write(audio[len(titles)*metaInt:])
+	return body.Bytes()
+}
+
+// whitelistServers allows the URLs of servers (and nothing else) for the rest of the test
+func whitelistServers(t *testing.T, servers ...*httptest.Server) {
+	t.Helper()
+	var rules []string
+	for _, server := range servers {
+		rules = append(rules, strings.TrimPrefix(server.URL, "http://"))
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "whitelist.txt"), []byte(strings.Join(rules, "\n")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err := youtubedl.LoadWhitelist(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newStation(t *testing.T) *httptest.Server {
+	audio := bytes.Repeat([]byte("0123456789"), 10)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/live", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/mpeg")
+		w.Header().Set("icy-name", "Test FM")
+		if r.Header.Get("Icy-MetaData") == "1" {
+			w.Header().Set("icy-metaint", "40")
+			w.Write(icyBody(40, audio, "Artist - First", "Artist - First"))
+			return
+		}
+		w.Write(audio)
+	})
+	mux.HandleFunc("/station.m3u", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/x-mpegurl")
+		io.WriteString(w, "#EXTM3U\n#EXTINF:-1,Test FM\n/live\n")
+	})
+	mux.HandleFunc("/station.pls", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, "[playlist]\nNumberOfEntries=1\nFile1=station.m3u\nTitle1=Test FM\n")
+	})
+	mux.HandleFunc("/watch", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, "<html></html>")
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	whitelistServers(t, server)
+	return server
+}
+
+func TestOpenICYStream(t *testing.T) {
+	server := newStation(t)
+	for _, path := range []string{"/live", "/station.m3u", "/station.pls"} {
+		stream, err := Open(server.URL + path)
+		if err != nil {
+			t.Fatalf("Open(%q) failed: %v", path, err)
+		}
+
+		var titles []string
+		stream.OnTitle = func(title string) { titles = append(titles, title) }
+		got, err := io.ReadAll(stream)
+		stream.Close()
+		if err != nil {
+			t.Fatalf("reading %q failed: %v", path, err)
+		}
+
+		if want := bytes.Repeat([]byte("0123456789"), 10); !bytes.Equal(got, want) {
+			t.Errorf("%q audio = %q, want %q", path, got, want)
+		}
+		if len(titles) != 1 || titles[0] != "Artist - First" {
+			t.Errorf("%q titles = %q, want one %q", path, titles, "Artist - First")
+		}
+		if stream.Name != "Test FM" || stream.URL != server.URL+"/live" || !stream.Live {
+			t.Errorf("%q stream = %q %q live=%v", path, stream.Name, stream.URL, stream.Live)
+		}
+	}
+}
+
+func TestOpenNotStream(t *testing.T) {
+	server := newStation(t)
+	if _, err := Open(server.URL + "/watch"); !errors.Is(err, ErrNotStream) {
+		t.Errorf("Open(/watch) err = %v, want ErrNotStream", err)
+	}
+	if _, ok := IsStream(server.URL + "/watch"); ok {
+		t.Errorf("IsStream(/watch) = true, want false")
+	}
+}
+
+func TestOpenLeavingWhitelist(t *testing.T) {
+	outside := newStation(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, outside.URL+"/live", http.StatusFound)
+	})
+	mux.HandleFunc("/station.m3u", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/x-mpegurl")
+		io.WriteString(w, outside.URL+"/live\n")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	whitelistServers(t, server) // Not outside
+
+	for _, path := range []string{"/redirect", "/station.m3u"} {
+		if _, err := Open(server.URL + path); !errors.Is(err, ErrNotWhitelisted) {
+			t.Errorf("Open(%q) err = %v, want ErrNotWhitelisted", path, err)
+		}
+	}
+}
+
+func TestProbe(t *testing.T) {
+	var gets atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/live", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/mpeg")
+		w.Header().Set("icy-name", "Test FM")
+		w.Header().Set("icy-metaint", "40")
+	})
+	mux.HandleFunc("/station.m3u", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/x-mpegurl")
+		io.WriteString(w, "/live\n")
+	})
+	mux.HandleFunc("/nohead", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "audio/ogg")
+		w.Header().Set("Content-Length", "4")
+		io.WriteString(w, "OggS")
+	})
+	mux.HandleFunc("/watch", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			gets.Add(1)
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	whitelistServers(t, server)
+
+	tests := []struct {
+		path string
+		name string
+		live bool
+		gets int32 // GET requests needed besides HEAD
+	}{
+		{"/live", "Test FM", true, 0},
+		{"/station.m3u", "Test FM", true, 2},
+		{"/nohead", "nohead", false, 1},
+	}
+	for _, test := range tests {
+		gets.Store(0)
+		stream, err := Probe(server.URL + test.path)
+		if err != nil {
+			t.Fatalf("Probe(%q) failed: %v", test.path, err)
+		}
+		if stream.Name != test.name || stream.Live != test.live {
+			t.Errorf("Probe(%q) = %q live=%v, want %q live=%v", test.path, stream.Name, stream.Live, test.name, test.live)
+		}
+		if got := gets.Load(); got != test.gets {
+			t.Errorf("Probe(%q) made %d GET requests, want %d", test.path, got, test.gets)
+		}
+	}
+
+	gets.Store(0)
+	for i := 0; i < 2; i++ {
+		if _, err := Probe(server.URL + "/watch"); !errors.Is(err, ErrNotStream) {
+			t.Errorf("Probe(/watch) err = %v, want ErrNotStream", err)
+		}
+	}
+	if got := gets.Load(); got != 0 {
+		t.Errorf("Probe(/watch) made %d GET requests, want 0", got)
+	}
+}
+
+func TestNotStreamCache(t *testing.T) {
+	notStreams.Lock()
+	saved := notStreams.urls
+	notStreams.urls = make(map[string]time.Time)
+	notStreams.Unlock()
+	defer func() {
+		notStreams.Lock()
+		notStreams.urls = saved
+		notStreams.Unlock()
+	}()
+
+	rememberNotStream("http://example.com/old")
+	notStreams.Lock()
+	notStreams.urls["http://example.com/old"] = time.Now().Add(-notStreamTTL)
+	notStreams.Unlock()
+	if knownNotStream("http://example.com/old") {
+		t.Error("expired URL is still known")
+	}
+
+	for i := 0; i < notStreamSize+10; i++ {
+		rememberNotStream("http://example.com/" + strconv.Itoa(i))
+	}
+	notStreams.Lock()
+	size := len(notStreams.urls)
+	notStreams.Unlock()
+	if size != notStreamSize {
+		t.Errorf("cache holds %d URLs, want %d", size, notStreamSize)
+	}
+	if knownNotStream("http://example.com/old") {
+		t.Error("oldest URL was not evicted")
+	}
+	if !knownNotStream("http://example.com/" + strconv.Itoa(notStreamSize+9)) {
+		t.Error("newest URL is not known")
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"log"
 	"os"
@@ -11,24 +12,39 @@ import (
 	"github.com/iotku/mumzic/database"
 	"github.com/iotku/mumzic/helper"
 	"github.com/iotku/mumzic/playback"
+	"github.com/iotku/mumzic/playlist"
+	"github.com/iotku/mumzic/youtubedl"
 	_ "github.com/mattn/go-sqlite3"
 	"layeh.com/gumble/gumble"
 	"layeh.com/gumble/gumbleutil"
 )
 
 func main() {
+	flag.DurationVar(&youtubedl.Timeout, "ytdl-timeout", youtubedl.Timeout, "timeout for yt-dlp metadata and search lookups")
+	flag.DurationVar(&youtubedl.DownloadTimeout, "ytdl-download-timeout", youtubedl.DownloadTimeout,
+		"timeout for yt-dlp downloads into the audio cache")
+	var importFile, exportFile string
+	flag.StringVar(&importFile, "import-playlist", "", "queue the tracks of an M3U, PLS or XSPF playlist file once connected")
+	flag.StringVar(&exportFile, "export-playlist", "", "write the queue to an M3U, PLS or XSPF playlist file when shutting down")
+
 	var channelPlayer *playback.Player
 	var bConfig *database.Config
 	var hostname, username string
+	stopSaving := func() {} // Stops saving the queue periodically, see Player.PersistQueue
 
 	cleanUp := func() {
+		youtubedl.Shutdown()
 		if bConfig != nil {
 			bConfig.Channel = channelPlayer.Client.Self.Channel.Name
 			bConfig.Save()
 		}
 
 		if channelPlayer != nil {
-			channelPlayer.Playlist.Save(bConfig.Hostname)
+			stopSaving()
+			helper.LogErr(channelPlayer.SaveQueue(), "Queue save")
+			if exportFile != "" {
+				helper.LogErr(playlist.Export(exportFile, channelPlayer.Playlist.Upcoming()), "Playlist export")
+			}
 		}
 
 		database.Close(database.ConfigDB)
@@ -57,8 +73,19 @@ func main() {
 				e.Client.Self.Move(e.Client.Channels.Find(bConfig.Channel))
 			}
 
+			stopSaving() // The previous connection's player, if any, mustn't keep writing its queue over ours
 			channelPlayer = playback.NewPlayer(e.Client, bConfig)
-			channelPlayer.Playlist.Load(bConfig.Hostname)
+			helper.LogErr(channelPlayer.RestoreQueue(), "Queue restore")
+			if importFile != "" {
+				tracks, skipped, err := playlist.Import(importFile)
+				helper.LogErr(err, "Playlist import")
+				duplicates := channelPlayer.Playlist.AddTracks(tracks, "")
+				log.Printf("Imported %d track(s) from %s, skipped %d, left out %d already queued\n", len(tracks)-duplicates, importFile, len(skipped), duplicates)
+				importFile = "" // only once, not on every reconnect
+			}
+			var saveCtx context.Context
+			saveCtx, stopSaving = context.WithCancel(context.Background())
+			go channelPlayer.PersistQueue(saveCtx)
 			log.Printf("audio player loaded! (%d files)\n", database.GetMaxID())
 		},
 		TextMessage: func(e *gumble.TextMessageEvent) {
@@ -2,6 +2,7 @@ package messages
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"errors"
 	"fmt"
@@ -15,6 +16,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/dhowden/tag"
 	"github.com/iotku/mumzic/youtubedl"
@@ -261,7 +263,26 @@ func DecodeImage(filePath string) (image.Image, error) {
 	return img, nil
 }
 
-func NowPlaying(path, human string, isRadioMode bool, count int) string {
+// Track holds the details of the current track shown by NowPlaying
+type Track struct {
+	Path    string
+	Human   string
+	IsRadio bool          // Radio mode is enabled
+	Count   int           // Songs queued
+	Speed   float64       // Playback speed multiplier, 0 or 1 when unchanged
+	Elapsed time.Duration // Position within the track, only shown when non zero
+
+	Duration time.Duration // Length of the track, 0 if unknown
+	Live     bool          // Live stream without a length
+	Uploader string        // Channel or uploader of URL tracks
+
+	IsStream    bool   // Plain HTTP audio stream (e.g. internet radio), these have no thumbnail
+	StreamTitle string // Title currently announced by the station
+	Chapter     string // Title of the chapter being played
+}
+
+func NowPlaying(track Track) string {
+	path, human := track.Path, track.Human
 	header := "<h2><u>Now Playing</u></h2><table><tr><td>"
 	var b strings.Builder
 	b.WriteString(`</td><td><table><tr><td><a href="`)
@@ -274,10 +295,33 @@ func NowPlaying(path, human string, isRadioMode bool, count int) string {
 	b.WriteString(html.EscapeString(human))
 	b.WriteString(`</a></td></tr>`)
 
-	if isRadioMode {
+	if track.Uploader != "" {
+		fmt.Fprintf(&b, `<tr><td>By: <b>%s</b></td></tr>`, html.EscapeString(track.Uploader))
+	}
+	if track.StreamTitle != "" {
+		fmt.Fprintf(&b, `<tr><td>On Air: <b>%s</b></td></tr>`, html.EscapeString(track.StreamTitle))
+	}
+	if track.Chapter != "" {
+		fmt.Fprintf(&b, `<tr><td>Chapter: <b>%s</b></td></tr>`, html.EscapeString(track.Chapter))
+	}
+	if track.Live {
+		b.WriteString(`<tr><td><b style="color:red">LIVE</b></td></tr>`)
+	}
+	if track.Elapsed > 0 && track.Duration > 0 {
+		fmt.Fprintf(&b, `<tr><td>Elapsed: <b>%s / %s</b></td></tr>`, FormatDuration(track.Elapsed), FormatDuration(track.Duration))
+	} else if track.Elapsed > 0 {
+		fmt.Fprintf(&b, `<tr><td>Elapsed: <b>%s</b></td></tr>`, FormatDuration(track.Elapsed))
+	} else if track.Duration > 0 {
+		fmt.Fprintf(&b, `<tr><td>Length: <b>%s</b></td></tr>`, FormatDuration(track.Duration))
+	}
+	if track.Speed != 0 && track.Speed != 1 {
+		fmt.Fprintf(&b, `<tr><td>Speed: <b>%sx</b></td></tr>`, strconv.FormatFloat(track.Speed, 'f', -1, 64))
+	}
+
+	if track.IsRadio {
 		b.WriteString(`<tr><td><b>Radio</b> Mode: <b>Enabled</b></td></tr>`)
 	} else {
-		fmt.Fprintf(&b, `<tr><td><b>%d</b> songs queued</td></tr>`, count)
+		fmt.Fprintf(&b, `<tr><td><b>%d</b> songs queued</td></tr>`, track.Count)
 	}
 	b.WriteString(`</table></td></tr></table>`)
 
@@ -286,8 +330,10 @@ func NowPlaying(path, human string, isRadioMode bool, count int) string {
 	var img image.Image
 	var err error
 
-	if strings.HasPrefix(path, "http") { // ytdlp thumbnail
-		img, err = youtubedl.GetYtDLThumbnail(path)
+	if track.IsStream {
+		// Nothing to show, but don't let ytdl try to download the stream
+	} else if strings.HasPrefix(path, "http") { // ytdlp thumbnail
+		img, err = youtubedl.GetYtDLThumbnail(context.Background(), path)
 	} else { // Local files
 		img, err = GetEmbdedImage(path) // Get embeded image
 		if err != nil {                 // Try looking for file
@@ -306,9 +352,18 @@ func NowPlaying(path, human string, isRadioMode bool, count int) string {
 	return header + artImg + b.String()
 }
 
+// FormatDuration formats d as m:ss or h:mm:ss
+func FormatDuration(d time.Duration) string {
+	seconds := int(d.Round(time.Second).Seconds())
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
 // GetEmbdedImage decodes an embeded image from a media file as an image.Image
 func GetEmbdedImage(filePath string) (image.Image, error) {
-    //#nosec G304 - We trust that the mediadb is a secure source of file paths
+	//#nosec G304 - We trust that the mediadb is a secure source of file paths
 	//              However, if we were to get filePath from a user controlled
 	//              source this expectation may not hold.
 	file, err := os.Open(filePath)
@@ -0,0 +1,89 @@
+package playback
+
+import (
+	"errors"
+	"log"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"github.com/iotku/mumzic/database"
+	"github.com/iotku/mumzic/helper"
+	"github.com/iotku/mumzic/playlist"
+	"github.com/iotku/mumzic/search"
+)
+
+// Autoplay weighs up to autoplayCandidates library tracks like the last one played, and tracks played within
+// coPlayedWindow tracks of it before, which score coPlayedScore for each time they were.
+const (
+	autoplayCandidates = 20
+	coPlayedWindow     = 3
+	coPlayedScore      = 2
+)
+
+// AutoplayTrack picks a track like the one at path to play after it, by artist, album, genre, year and what was
+// played around it before. It returns an ID or URL for Playlist.AddNext, or "" if nothing alike was found.
+func (player *Player) AutoplayTrack(path, human string) (string, error) {
+	seed, ok := search.TrackByPath(path)
+	if !ok { // URL, or a file which isn't in the library anymore
+		seed = search.Track{Artist: player.currentArtist(human), Path: path}
+	}
+	recent := database.GetRecentPaths(player.Config.Hostname, radioNoRepeat)
+	similar, err := search.SimilarTracks(seed, recent, autoplayCandidates)
+	if err != nil && !errors.Is(err, search.ErrNoMedia) {
+		return "", err
+	}
+
+	scores := make(map[string]int)
+	next := make(map[string]string) // Path to ID or URL
+	for _, v := range similar {
+		scores[v.Path], next[v.Path] = v.Score, strconv.Itoa(v.ID)
+	}
+	played := make(map[string]bool)
+	for _, v := range recent {
+		played[v] = true
+	}
+	for coPath, count := range database.GetCoPlayed(player.Config.Hostname, path, coPlayedWindow, autoplayCandidates) {
+		if played[coPath] {
+			continue
+		}
+		if _, ok := next[coPath]; !ok {
+			if strings.HasPrefix(coPath, "http") {
+				next[coPath] = coPath
+			} else if id := search.GetTrackIDByPath(coPath); id != 0 {
+				next[coPath] = strconv.Itoa(id)
+			} else {
+				continue
+			}
+		}
+		scores[coPath] += count * coPlayedScore
+	}
+	if len(scores) == 0 {
+		return "", nil
+	}
+
+	paths := make([]string, 0, len(scores))
+	weights := make([]float64, 0, len(scores))
+	for p, score := range scores {
+		paths = append(paths, p)
+		weights = append(weights, float64(score))
+	}
+	return next[paths[pickWeighted(weights, rand.Float64())]], nil
+}
+
+// queueAutoplay adds a track like the current one after it, or a random one if nothing alike was found
+func (player *Player) queueAutoplay() {
+	next, err := player.AutoplayTrack(player.Playlist.GetCurrentPath(), player.Playlist.GetCurrentHuman())
+	if err == nil && next == "" {
+		var id int
+		id, err = player.RadioTrackID()
+		next = strconv.Itoa(id)
+	}
+	if err == nil {
+		err = player.Playlist.AddNext(next, "")
+	}
+	if err != nil && !playlist.IsWarning(err) {
+		helper.ChanMsg(player.Client, "<b style=\"color:red\">Error Adding Autoplay Track: </b>"+err.Error())
+		log.Println("Autoplay failed to add a track:", err)
+	}
+}
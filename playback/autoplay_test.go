@@ -0,0 +1,31 @@
+package playback
+
+import (
+	"testing"
+
+	"github.com/iotku/mumzic/database"
+	"github.com/iotku/mumzic/database/dbtest"
+)
+
+func TestAutoplayTrack(t *testing.T) {
+	dbtest.UseConfigDB(t)
+	dbtest.UseMediaDB(t, []dbtest.Track{
+		{Artist: "Miles Davis", Album: "Kind of Blue", Title: "So What", Path: "/music/so-what.flac"},
+		{Artist: "Miles Davis", Album: "Kind of Blue", Title: "Blue in Green", Path: "/music/blue-in-green.flac"},
+		{Artist: "Miles Davis", Album: "Bitches Brew", Title: "Spanish Key", Path: "/music/spanish-key.flac"},
+		{Artist: "Daft Punk", Album: "Discovery", Title: "One More Time", Path: "/music/one-more-time.flac"},
+	}, false)
+
+	player := &Player{Config: &database.Config{Hostname: "example.com"}}
+	database.FinishHistory(database.StartHistory(player.Config.Hostname, "/music/blue-in-green.flac", "Miles Davis - Blue in Green", "Miles Davis", ""), database.OutcomeCompleted)
+
+	// Blue in Green is the most alike but was just played, One More Time has nothing in common
+	for i := 0; i < 10; i++ {
+		if next, err := player.AutoplayTrack("/music/so-what.flac", "Miles Davis - So What"); err != nil || next != "3" {
+			t.Fatalf("AutoplayTrack = %q, %v, want 3 (Spanish Key)", next, err)
+		}
+	}
+	if next, err := player.AutoplayTrack("https://youtu.be/x", "Aphex Twin - Xtal"); err != nil || next != "" {
+		t.Errorf("AutoplayTrack of an unknown artist = %q, %v, want nothing", next, err)
+	}
+}
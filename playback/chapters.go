@@ -0,0 +1,72 @@
+package playback
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/iotku/mumzic/helper"
+	"github.com/iotku/mumzic/resolver"
+	"layeh.com/gumble/gumbleffmpeg"
+)
+
+// Chapters returns the chapters of the current track, nil if it has none
+func (player *Player) Chapters() []resolver.Chapter {
+	player.mu.RLock()
+	defer player.mu.RUnlock()
+	return player.track.Chapters
+}
+
+// CurrentChapter returns the index of the chapter being played, -1 if there is none
+func (player *Player) CurrentChapter() int {
+	return chapterAt(player.Chapters(), player.Elapsed())
+}
+
+func chapterAt(chapters []resolver.Chapter, position time.Duration) int {
+	for i := len(chapters) - 1; i >= 0; i-- {
+		if position >= chapters[i].Start {
+			return i
+		}
+	}
+	return -1
+}
+
+// SeekChapter restarts the current track at the start of chapter index
+func (player *Player) SeekChapter(index int) error {
+	chapters := player.Chapters()
+	if len(chapters) == 0 {
+		return errors.New("this track has no chapters")
+	}
+	if index < 0 || index >= len(chapters) {
+		return errors.New("invalid chapter: Valid range [0-" + strconv.Itoa(len(chapters)-1) + "]")
+	}
+	player.Seek(chapters[index].Start)
+	return nil
+}
+
+// watchChapters updates the Now Playing comment whenever another chapter of the track played by stream starts.
+// It gives up once ctx is cancelled or another stream replaced stream.
+func (player *Player) watchChapters(ctx context.Context, stream *gumbleffmpeg.Stream) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	last := -1
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		player.mu.RLock()
+		current := player.stream == stream
+		player.mu.RUnlock()
+		if !current {
+			return
+		}
+		if chapter := player.CurrentChapter(); chapter != last {
+			last = chapter
+			helper.SetComment(player.Client, player.NowPlaying())
+		}
+	}
+}
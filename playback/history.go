@@ -0,0 +1,39 @@
+package playback
+
+import (
+	"strings"
+
+	"github.com/iotku/mumzic/database"
+)
+
+// startHistory records the current playlist item as playing
+func (player *Player) startHistory() {
+	human := player.Playlist.GetCurrentHuman()
+	id := database.StartHistory(player.Config.Hostname, player.Playlist.GetCurrentPath(), human, player.currentArtist(human),
+		player.Playlist.GetCurrentRequester())
+	player.mu.Lock()
+	player.historyID = id
+	player.mu.Unlock()
+}
+
+// currentArtist returns the tagged artist or uploader of the current track, or guesses it from its human title
+func (player *Player) currentArtist(human string) string {
+	player.mu.RLock()
+	artist := player.track.Uploader
+	player.mu.RUnlock()
+	if before, _, ok := strings.Cut(human, " - "); ok && artist == "" { // Library tracks are "Artist - Title"
+		artist = before
+	}
+	return artist
+}
+
+// finishHistory records how the track being played ended, if it was recorded and isn't finished already
+func (player *Player) finishHistory(outcome string) {
+	player.mu.Lock()
+	id := player.historyID
+	player.historyID = 0
+	player.mu.Unlock()
+	if id != 0 {
+		database.FinishHistory(id, outcome)
+	}
+}
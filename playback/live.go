@@ -0,0 +1,70 @@
+package playback
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/iotku/mumzic/helper"
+	"github.com/iotku/mumzic/youtubedl"
+	"layeh.com/gumble/gumbleffmpeg"
+)
+
+// Reconnecting a dropped live stream is given up after maxLiveRetries attempts in a row.
+// Attempts only count as in a row if the stream dropped again within liveStableAfter.
+const (
+	maxLiveRetries  = 3
+	liveStableAfter = time.Minute
+)
+
+// reconnectLive restarts the current live stream after the connection of stream dropped, a live stream never ends by
+// itself. It returns false if playback should move along the playlist instead.
+func (player *Player) reconnectLive(stopCtx context.Context, stream *gumbleffmpeg.Stream) bool {
+	if stream.Elapsed() >= liveStableAfter {
+		player.liveRetries = 0
+	}
+	if player.liveRetries >= maxLiveRetries {
+		helper.ChanMsg(player.Client, "Live stream dropped, giving up after "+strconv.Itoa(maxLiveRetries)+" reconnects.")
+		player.liveRetries = 0
+		return false
+	}
+	player.liveRetries++
+
+	select {
+	case <-stopCtx.Done():
+		return true // Stopped while waiting, whoever stopped us takes over
+	case <-time.After(time.Duration(player.liveRetries) * 2 * time.Second):
+	}
+	path := player.Playlist.GetCurrentPath()
+	log.Println("Reconnecting live stream", path, "attempt", player.liveRetries)
+	helper.ChanMsg(player.Client, "Live stream dropped, reconnecting...")
+	return player.start(path, 0)
+}
+
+// QueueDuration returns how long the current and queued tracks will play, as far as it is known.
+// Live streams and tracks of unknown length (local files, tracks not looked up yet) are counted in unknown instead.
+func (player *Player) QueueDuration() (total time.Duration, unknown int) {
+	upcoming := player.Playlist.Upcoming()
+	if len(upcoming) == 0 {
+		return 0, 0
+	}
+
+	if track := player.currentTrack(); player.IsPlaying() && track.Duration > 0 && !track.Live {
+		total += track.Duration - player.Elapsed()
+	} else {
+		unknown++
+	}
+	for _, item := range upcoming[1:] {
+		metadata, ok := youtubedl.CachedMetadata(item[0])
+		if !ok || metadata.Live() || metadata.Length() == 0 {
+			unknown++
+			continue
+		}
+		total += metadata.Length()
+	}
+	if speed := player.AudioSettings().Speed(); speed > 0 {
+		total = time.Duration(float64(total) / speed)
+	}
+	return total, unknown
+}
@@ -0,0 +1,72 @@
+package playback
+
+import (
+	"context"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/iotku/mumzic/helper"
+	"github.com/iotku/mumzic/playlist"
+)
+
+// saveInterval is how often PersistQueue saves the queue, so that at most this much is lost in a crash
+const saveInterval = 10 * time.Second
+
+// queueSnapshot returns the queue with how far into the current track playback is. It holds player.mu, so the stream
+// can't be replaced meanwhile, and only saves the position if the stream still plays the current item (rather than the
+// one before it, which happens between a track ending and the next one starting).
+func (player *Player) queueSnapshot() playlist.Saved {
+	player.mu.RLock()
+	defer player.mu.RUnlock()
+	playing := player.playing()
+	saved := player.Playlist.Snapshot(0, playing)
+	if playing && !player.track.Live && len(saved.Queue) != 0 && helper.StripHTMLTags(saved.Queue[0][0]) == player.path {
+		saved.Offset = player.elapsed().Truncate(time.Second)
+	}
+	return saved
+}
+
+// SaveQueue saves the queue for this server, see RestoreQueue
+func (player *Player) SaveQueue() error {
+	return player.queueSnapshot().Save(player.Config.Hostname)
+}
+
+// PersistQueue saves the queue every saveInterval while it (or the position in the current track) changes,
+// until ctx is done
+func (player *Player) PersistQueue(ctx context.Context) {
+	ticker := time.NewTicker(saveInterval)
+	defer ticker.Stop()
+	var last playlist.Saved
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		saved := player.queueSnapshot()
+		if reflect.DeepEqual(saved, last) {
+			continue
+		}
+		if err := saved.Save(player.Config.Hostname); err != nil {
+			log.Println("Saving the queue failed:", err)
+			continue
+		}
+		last = saved
+	}
+}
+
+// RestoreQueue loads the queue saved for this server, resuming the current track near where it was if it was playing
+func (player *Player) RestoreQueue() error {
+	saved, err := playlist.LoadSaved(player.Config.Hostname)
+	if err != nil || len(saved.Queue) == 0 {
+		return err
+	}
+	log.Printf("Restoring the previous queue (%d items)\n", len(saved.Queue))
+	player.Playlist.Restore(saved)
+	if saved.Playing {
+		player.playAt(player.Playlist.GetCurrentPath(), saved.Offset)
+	}
+	return nil
+}
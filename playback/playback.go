@@ -10,11 +10,16 @@ import (
 	"sync"
 	"time"
 
+	"github.com/iotku/mumzic/audio"
+	"github.com/iotku/mumzic/cache"
 	"github.com/iotku/mumzic/database"
 	"github.com/iotku/mumzic/helper"
+	"github.com/iotku/mumzic/httpstream"
 	"github.com/iotku/mumzic/messages"
 	"github.com/iotku/mumzic/playlist"
+	"github.com/iotku/mumzic/resolver"
 	"github.com/iotku/mumzic/search"
+	"github.com/iotku/mumzic/segments"
 	"github.com/iotku/mumzic/youtubedl"
 	"layeh.com/gumble/gumble"
 	"layeh.com/gumble/gumble/MumbleProto"
@@ -30,6 +35,19 @@ type Player struct {
 	Volume   float32
 	IsRadio  bool
 	Config   *database.Config
+	path     string        // Playlist path of the current stream
+	offset   time.Duration // Position in the media the current stream started at
+	speed    float64       // Playback speed of the current stream
+
+	RadioFilter search.Query // Limits the tracks radio mode picks from, see search.ParseQuery
+	Autoplay    bool         // Add a track like the last one when the queue runs out (see AutoplayTrack)
+
+	track       resolver.Track // Current URL track, zero for local files
+	streamTitle string         // Title last announced by the station of an HTTP stream
+	liveRetries int            // Reconnects of the current live stream since it last played steadily
+	historyID   int64          // History entry of the current track, 0 once finished (see database.StartHistory)
+
+	prefetch *prefetched // Buffered audio of the next URL track
 
 	// Syncronization
 	mu         sync.RWMutex
@@ -96,13 +114,16 @@ func (player *Player) TargetUsers() {
 
 func NewPlayer(client *gumble.Client, config *database.Config) *Player {
 	ctx, cancel := context.WithCancel(context.Background())
+	cache.Setup(int64(config.CacheSize) << 20)
 	return &Player{
 		stream:  nil,
 		Client:  client,
 		targets: make([]*gumble.User, 0),
 		Playlist: playlist.List{
-			Playlist: make([][]string, 0),
-			Position: 0,
+			Playlist:    make([][]string, 0),
+			Position:    0,
+			MaxDuration: time.Duration(config.MaxDuration) * time.Minute,
+			Duplicates:  config.Duplicates,
 		},
 		Volume:     config.Volume,
 		IsRadio:    false,
@@ -123,6 +144,11 @@ func (player *Player) IsStopped() bool {
 func (player *Player) IsPlaying() bool {
 	player.mu.RLock()
 	defer player.mu.RUnlock()
+	return player.playing()
+}
+
+// playing is IsPlaying for callers holding player.mu
+func (player *Player) playing() bool {
 	return player.isPlaying && player.stream != nil && player.stream.State() == gumbleffmpeg.StatePlaying
 }
 
@@ -140,15 +166,18 @@ func (player *Player) WaitForStop() {
 	}
 
 	// Wait for either stream completion or stop signal
+	player.mu.RLock()
+	stream, stopCtx := player.stream, player.stopCtx
+	player.mu.RUnlock()
 	done := make(chan struct{})
 	go func() {
 		defer close(done)
-		player.stream.Wait()
+		stream.Wait()
 	}()
 
 	select {
 	case <-done: // Stream finished
-	case <-player.stopCtx.Done(): // Stop requested
+	case <-stopCtx.Done(): // Stop requested
 		player.ensureStreamStopped()
 		return
 	}
@@ -159,7 +188,8 @@ func (player *Player) WaitForStop() {
 	}
 
 	player.mu.RLock()
-	shouldContinue := player.isPlaying
+	shouldContinue := player.isPlaying && stopCtx.Err() == nil // a new stream may have replaced ours (e.g. Seek)
+	live := player.track.Live
 	player.mu.RUnlock()
 
 	if !shouldContinue {
@@ -167,14 +197,26 @@ func (player *Player) WaitForStop() {
 		return
 	}
 
+	if live && player.reconnectLive(stopCtx, stream) {
+		return
+	}
+	player.finishHistory(database.OutcomeCompleted)
+
 	if player.IsRadio {
-		err := player.Playlist.AddNext(strconv.Itoa(search.GetRandomTrackIDs(1)[0]))
-		if err != nil {
+		id, err := player.RadioTrackID()
+		if err == nil {
+			err = player.Playlist.AddNext(strconv.Itoa(id), "")
+		}
+		if err != nil && !playlist.IsWarning(err) {
 			helper.ChanMsg(player.Client, "<b style=\"color:red\">Error Adding Radio Track: </b>"+err.Error())
 			log.Println("Radio failed to Playlist.AddNext a random track ID, stale database?: ", err)
 		}
 	}
 
+	if player.Autoplay && !player.IsRadio && !player.Playlist.HasNext() {
+		player.queueAutoplay()
+	}
+
 	if player.Playlist.HasNext() {
 		player.Playlist.Next()
 		player.PlayCurrent()
@@ -184,39 +226,131 @@ func (player *Player) WaitForStop() {
 }
 
 func (player *Player) Play(path string) {
-	if player.IsPlaying() {
-		player.requestStop()
+	player.playAt(path, 0)
+}
+
+// playAt plays path from offset as a new track, announcing it
+func (player *Player) playAt(path string, offset time.Duration) {
+	player.liveRetries = 0
+	player.finishHistory(database.OutcomeSkipped)
+	if player.start(path, offset) {
+		player.startHistory()
+		nowPlaying := player.NowPlaying()
+		helper.ChanMsg(player.Client, nowPlaying)
+		helper.SetComment(player.Client, nowPlaying)
+	}
+}
+
+// Seek restarts the current track at offset without announcing it or moving along the playlist
+func (player *Player) Seek(offset time.Duration) {
+	if player.Playlist.IsEmpty() {
+		return
+	}
+	player.mu.RLock()
+	live := player.track.Live
+	player.mu.RUnlock()
+	if offset < 0 || live {
+		offset = 0
+	}
+	player.start(player.Playlist.GetCurrentPath(), offset)
+}
+
+// seekIfCurrent restarts path at offset if stream is still playing it, returning false if playback moved on.
+// Watchers running alongside a stream use it, so they never seek a track which replaced theirs.
+func (player *Player) seekIfCurrent(stream *gumbleffmpeg.Stream, path string, offset time.Duration) bool {
+	player.mu.RLock()
+	current := player.isPlaying && player.stream == stream
+	player.mu.RUnlock()
+	if !current || player.Playlist.IsEmpty() || helper.StripHTMLTags(player.Playlist.GetCurrentPath()) != path {
+		return false
+	}
+	return player.start(path, offset)
+}
+
+// start stops any current stream and plays path from offset, returning whether playback started
+func (player *Player) start(path string, offset time.Duration) bool {
+	wasPlaying := player.IsPlaying()
+	player.requestStop() // Also ends the watchers of a track which ended by itself, its context is still live
+	if wasPlaying {
+		player.ensureStreamStopped()
 		player.waitForActualStop(3 * time.Second)
 	}
 
 	path = helper.StripHTMLTags(path)
+	player.dropPrefetch(path)
+	player.mu.Lock()
+	player.path, player.offset = path, offset
+	player.track, player.streamTitle = resolver.Track{}, ""
+	player.mu.Unlock()
 	var err error
 	if strings.HasPrefix(path, "http") {
-		err = player.PlayYT(path)
+		err = player.PlayURL(path)
 	} else {
 		err = player.PlayFile(path)
 	}
 
 	if err != nil {
 		helper.ChanMsg(player.Client, "<b style=\"color:red\">Error: </b>"+err.Error())
-		return
+		return false
 	}
 
 	player.markPlaying()
-	nowPlaying := player.NowPlaying()
-	helper.ChanMsg(player.Client, nowPlaying)
-	helper.SetComment(player.Client, nowPlaying)
 	go player.WaitForStop()
+	player.mu.RLock()
+	if !player.track.Live {
+		go player.watchPrefetch(player.stopCtx, path, player.track.Duration)
+	}
+	if len(player.track.Chapters) != 0 {
+		go player.watchChapters(player.stopCtx, player.stream)
+	}
+	if provider := segments.NewProvider(player.Config.Segments); provider != nil && player.track.VideoID != "" {
+		go player.watchSegments(player.stopCtx, player.stream, path, provider, player.track.VideoID)
+	}
+	player.mu.RUnlock()
+	return true
+}
+
+// Elapsed returns the position within the current track. The stream reports how much audio it has sent,
+// which covers more (or less) of the track when the playback speed is changed.
+func (player *Player) Elapsed() time.Duration {
+	player.mu.RLock()
+	defer player.mu.RUnlock()
+	return player.elapsed()
+}
+
+// elapsed is Elapsed for callers holding player.mu
+func (player *Player) elapsed() time.Duration {
+	if player.stream == nil {
+		return 0
+	}
+	return player.offset + time.Duration(float64(player.stream.Elapsed())*player.speed)
 }
 
-func (player *Player) PlayNow(track string) error {
+// AudioSettings returns the audio filters configured for this server
+func (player *Player) AudioSettings() audio.Settings {
+	return audio.NewSettings(player.Config.Equalizer, player.Config.Filters)
+}
+
+// SetAudioSettings saves settings to the server configuration and applies them to the current track
+func (player *Player) SetAudioSettings(settings audio.Settings) {
+	player.Config.Equalizer = settings.Equalizer
+	player.Config.Filters = settings.Filters()
+	player.Config.Save()
+
+	if player.IsPlaying() {
+		player.Seek(player.Elapsed())
+	}
+}
+
+func (player *Player) PlayNow(track, requester string) error {
+	player.Playlist.Checkpoint("playnow")
 	player.Stop(true)
-	err := player.Playlist.AddNext(track)
-	if player.IsStopped() && err == nil {
+	err := player.Playlist.AddNext(track, requester)
+	if player.IsStopped() && (err == nil || playlist.IsWarning(err)) {
 		if !player.Playlist.HasNext() {
 			player.PlayCurrent()
 		} else {
-			player.Playlist.Skip(1)
+			player.Playlist.Next()
 			player.PlayCurrent()
 		}
 	}
@@ -225,15 +359,49 @@ func (player *Player) PlayNow(track string) error {
 }
 
 func (player *Player) NowPlaying() string {
-	currentPath := player.Playlist.GetCurrentPath()
-	currentHuman := player.Playlist.GetCurrentHuman()
-	isRadio := player.IsRadio
-	count := player.Playlist.Count()
+	return messages.NowPlaying(player.currentTrack())
+}
+
+// NowPlayingElapsed is NowPlaying including how far into the track playback is
+func (player *Player) NowPlayingElapsed() string {
+	track := player.currentTrack()
+	track.Elapsed = player.Elapsed()
+	return messages.NowPlaying(track)
+}
 
-	return messages.NowPlaying(currentPath, currentHuman, isRadio, count)
+func (player *Player) currentTrack() messages.Track {
+	player.mu.RLock()
+	track := messages.Track{
+		Path:    player.Playlist.GetCurrentPath(),
+		Human:   player.Playlist.GetCurrentHuman(),
+		IsRadio: player.IsRadio,
+		Count:   player.Playlist.Count(),
+		Speed:   player.speed,
+
+		Duration:    player.track.Duration,
+		Live:        player.track.Live,
+		Uploader:    player.track.Uploader,
+		IsStream:    player.track.Stream,
+		StreamTitle: player.streamTitle,
+	}
+	chapters := player.track.Chapters
+	player.mu.RUnlock()
+	if chapter := player.CurrentChapter(); chapter >= 0 {
+		track.Chapter = chapters[chapter].Title
+	}
+	return track
 }
 
+// Stop ends playback of the current track, dropping any prefetched track.
+// With shouldStop false the player carries on with the next track (e.g. in radio mode).
 func (player *Player) Stop(shouldStop bool) {
+	player.stop(shouldStop)
+	player.dropPrefetch("")
+}
+
+// stop is Stop keeping the prefetched track, for when the next track is played right away
+func (player *Player) stop(shouldStop bool) {
+	player.finishHistory(database.OutcomeSkipped)
 	if shouldStop {
 		player.requestStop()
 	} else {
@@ -256,15 +424,15 @@ func (player *Player) PlayFile(path string) error {
 		return errors.New("not found")
 	}
 
-	player.stream = gumbleffmpeg.New(player.Client, gumbleffmpeg.SourceFile(path))
-	player.stream.Volume = player.Volume
-	err := player.stream.Play()
-	return err
+	return player.startStream(audio.Input{Path: path})
 }
 
 func (player *Player) Skip(amount int) {
+	if player.Autoplay && !player.IsRadio && !player.Playlist.IsEmpty() && !player.Playlist.HasNext() {
+		player.queueAutoplay()
+	}
 	if player.Playlist.HasNext() && !player.IsRadio {
-		player.Stop(true)
+		player.stop(true)
 		player.Playlist.Skip(amount)
 		player.PlayCurrent()
 	} else if player.IsRadio {
@@ -274,17 +442,57 @@ func (player *Player) Skip(amount int) {
 	}
 }
 
-// PlayYT streams a URL through ytdl
-func (player *Player) PlayYT(url string) error {
+// PlayURL plays a URL with the first resolver handling it (see package resolver)
+func (player *Player) PlayURL(url string) error {
 	url = helper.StripHTMLTags(url)
 	if !youtubedl.IsWhiteListedURL(url) {
 		return errors.New("URL Doesn't Meet whitelist")
 	}
 
-	player.stream = gumbleffmpeg.New(player.Client, youtubedl.GetYtDLSource(url))
-	player.stream.Volume = player.Volume
-	err := player.stream.Play()
-	return err
+	if prefetch := player.takePrefetch(url); prefetch != nil {
+		track, _ := resolver.Resolve(context.Background(), url) // cached from prefetching
+		player.setTrack(track)
+		return player.startStream(audio.Input{Reader: prefetch})
+	}
+
+	source, err := resolver.Open(context.Background(), url)
+	if err != nil {
+		return err
+	}
+	player.setTrack(source.Track)
+	if stream, ok := source.Reader.(*httpstream.Stream); ok {
+		stream.OnTitle = player.setStreamTitle
+	}
+	return player.startStream(audio.Input{Path: source.Path, Cmd: source.Cmd, Reader: source.Reader})
+}
+
+// setTrack sets the URL track which is about to be played
+func (player *Player) setTrack(track resolver.Track) {
+	player.mu.Lock()
+	player.track = track
+	player.mu.Unlock()
+}
+
+// setStreamTitle updates the Now Playing comment when a station announces a new song
+func (player *Player) setStreamTitle(title string) {
+	player.mu.Lock()
+	player.streamTitle = title
+	player.mu.Unlock()
+	if player.IsPlaying() {
+		helper.SetComment(player.Client, player.NowPlaying())
+	}
+}
+
+// startStream plays in from player.offset with the configured audio settings applied
+func (player *Player) startStream(in audio.Input) error {
+	settings := player.AudioSettings()
+	stream := audio.NewStream(player.Client, in, settings, player.offset)
+	stream.Volume = player.Volume
+	player.mu.Lock()
+	player.stream = stream
+	player.speed = settings.Speed()
+	player.mu.Unlock()
+	return stream.Play()
 }
 
 func (player *Player) SetVolume(value float32) {
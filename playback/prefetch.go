@@ -0,0 +1,126 @@
+package playback
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/iotku/mumzic/audio"
+	"github.com/iotku/mumzic/cache"
+	"github.com/iotku/mumzic/resolver"
+	"github.com/iotku/mumzic/youtubedl"
+)
+
+// How long before the end of a track the next URL track starts buffering
+const prefetchWindow = 30 * time.Second
+
+// prefetched is the buffered audio of an upcoming URL track
+type prefetched struct {
+	url    string
+	reader *audio.Prefetch
+}
+
+// trackDuration returns the length of a local file, 0 if unknown
+func trackDuration(path string) time.Duration {
+	duration, err := audio.Duration(path)
+	if err != nil {
+		return 0
+	}
+	return duration
+}
+
+// watchPrefetch waits until the current track (path, lasting duration) nearly finished, then starts buffering the next one.
+// Until the track ends the next one is buffered again whenever the playlist changes (e.g. clear or undo), and
+// a buffer no longer matching the next entry is dropped. The duration of local files is looked up here, it is 0 for them.
+// It gives up once ctx is cancelled, which happens whenever the current stream is stopped or replaced.
+func (player *Player) watchPrefetch(ctx context.Context, path string, duration time.Duration) {
+	if duration == 0 && !strings.HasPrefix(path, "http") {
+		duration = trackDuration(path)
+	}
+	if duration <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	var tried string // Next entry prefetchNext last ran for
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		player.mu.RLock()
+		speed := player.speed
+		player.mu.RUnlock()
+		if remaining := time.Duration(float64(duration-player.Elapsed()) / speed); remaining > prefetchWindow {
+			continue
+		}
+		next := player.Playlist.GetNextPath()
+		player.dropPrefetch(next)
+		if next != tried {
+			tried = next
+			player.prefetchNext(next)
+		}
+	}
+}
+
+// prefetchNext starts yt-dlp for url, the next playlist entry, if it is a yt-dlp track which isn't cached
+func (player *Player) prefetchNext(url string) {
+	if !strings.HasPrefix(url, "http") || !youtubedl.IsWhiteListedURL(url) {
+		return
+	}
+	if _, ok := cache.Lookup(url); ok {
+		return
+	}
+	// Files and streams are opened quickly (and streams are live, so buffering them ahead would only add delay)
+	if track, err := resolver.Resolve(context.Background(), url); err != nil || track.Resolver != (resolver.YtDL{}).Name() || track.Live {
+		return
+	}
+
+	player.mu.Lock()
+	defer player.mu.Unlock()
+	if player.prefetch != nil {
+		if player.prefetch.url == url {
+			return
+		}
+		player.prefetch.reader.Close() //#nosec G104 -- always nil
+	}
+
+	reader, err := audio.StartPrefetch(youtubedl.GetYtDLCommand(context.Background(), url))
+	if err != nil {
+		log.Println("Prefetching", url, "failed:", err)
+		player.prefetch = nil
+		return
+	}
+	player.prefetch = &prefetched{url, reader}
+}
+
+// dropPrefetch stops buffering and frees the buffered audio, unless it is for url ("" drops any prefetch)
+func (player *Player) dropPrefetch(url string) {
+	player.mu.Lock()
+	defer player.mu.Unlock()
+	if player.prefetch == nil || player.prefetch.url == url && url != "" {
+		return
+	}
+	player.prefetch.reader.Close() //#nosec G104 -- always nil
+	player.prefetch = nil
+}
+
+// takePrefetch returns the buffered audio for url if it was prefetched, discarding any other prefetch
+func (player *Player) takePrefetch(url string) *audio.Prefetch {
+	player.mu.Lock()
+	defer player.mu.Unlock()
+	prefetch := player.prefetch
+	player.prefetch = nil
+	if prefetch == nil {
+		return nil
+	}
+	if prefetch.url != url {
+		prefetch.reader.Close() //#nosec G104 -- always nil
+		return nil
+	}
+	return prefetch.reader
+}
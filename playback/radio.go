@@ -0,0 +1,67 @@
+package playback
+
+import (
+	"errors"
+	"math/rand"
+
+	"github.com/iotku/mumzic/database"
+	"github.com/iotku/mumzic/search"
+)
+
+// Radio mode doesn't pick any of the last radioNoRepeat tracks played, unless nothing else matches its filter.
+// Each pick is a weighted choice between radioCandidates random tracks.
+const (
+	radioNoRepeat   = 100
+	radioCandidates = 20
+)
+
+// RadioTrackID picks the next track of radio mode from the tracks matching RadioFilter
+func (player *Player) RadioTrackID() (int, error) {
+	hostname := player.Config.Hostname
+	recent := database.GetRecentPaths(hostname, radioNoRepeat)
+	candidates, err := search.RandomTracks(player.RadioFilter, recent, radioCandidates)
+	if err == nil && len(candidates) == 0 && len(recent) != 0 { // Everything matching was played recently
+		candidates, err = search.RandomTracks(player.RadioFilter, nil, radioCandidates)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(candidates) == 0 {
+		return 0, errors.New("no tracks match " + player.RadioFilter.String())
+	}
+
+	paths := make([]string, len(candidates))
+	for i, v := range candidates {
+		paths[i] = v.Path
+	}
+	likes := database.GetLikeCounts(hostname, paths)
+	plays := database.GetPathStats(hostname, paths)
+	weights := make([]float64, len(candidates))
+	for i, path := range paths {
+		weights[i] = radioWeight(likes[path], plays[path])
+	}
+	return candidates[pickWeighted(weights, rand.Float64())].ID, nil
+}
+
+// radioWeight favours tracks liked by more users, and tracks usually played to the end over those usually skipped
+func radioWeight(likes int, plays database.PlayStats) float64 {
+	completed := plays.Plays - plays.Skips
+	ratio := float64(1+completed) / float64(1+plays.Skips)
+	return (1 + float64(likes)) * min(max(ratio, 0.25), 4)
+}
+
+// pickWeighted returns the index of weights that r (from 0 to 1) falls on, weights taking space by their size
+func pickWeighted(weights []float64, r float64) int {
+	var total float64
+	for _, v := range weights {
+		total += v
+	}
+	r *= total
+	for i, v := range weights {
+		if r < v {
+			return i
+		}
+		r -= v
+	}
+	return len(weights) - 1
+}
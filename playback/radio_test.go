@@ -0,0 +1,49 @@
+package playback
+
+import (
+	"testing"
+
+	"github.com/iotku/mumzic/database"
+)
+
+func TestRadioWeight(t *testing.T) {
+	tests := []struct {
+		likes    int
+		plays    database.PlayStats
+		expected float64
+	}{
+		{0, database.PlayStats{}, 1},
+		{2, database.PlayStats{}, 3},
+		{0, database.PlayStats{Plays: 3}, 4},
+		{0, database.PlayStats{Plays: 9}, 4},
+		{0, database.PlayStats{Plays: 3, Skips: 3}, 0.25},
+		{1, database.PlayStats{Plays: 2, Skips: 1}, 2},
+	}
+
+	for _, tt := range tests {
+		if got := radioWeight(tt.likes, tt.plays); got != tt.expected {
+			t.Errorf("radioWeight(%d, %+v) = %v, want %v", tt.likes, tt.plays, got, tt.expected)
+		}
+	}
+}
+
+func TestPickWeighted(t *testing.T) {
+	weights := []float64{1, 3, 0, 4}
+	tests := []struct {
+		r        float64
+		expected int
+	}{
+		{0, 0},
+		{0.1, 0},
+		{0.125, 1},
+		{0.49, 1},
+		{0.5, 3},
+		{0.999, 3},
+	}
+
+	for _, tt := range tests {
+		if got := pickWeighted(weights, tt.r); got != tt.expected {
+			t.Errorf("pickWeighted(%v, %v) = %d, want %d", weights, tt.r, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package playback
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/iotku/mumzic/helper"
+	"github.com/iotku/mumzic/messages"
+	"github.com/iotku/mumzic/segments"
+	"layeh.com/gumble/gumbleffmpeg"
+)
+
+// Segments shorter than this aren't worth restarting the stream for
+const minSegment = 2 * time.Second
+
+// watchSegments looks up the segments of videoID, played from path by stream, and seeks past each one as it is reached,
+// noting the skip in chat. It gives up once ctx is cancelled, which happens whenever the current stream is stopped or
+// replaced (the seek included), or when it finds another track playing.
+func (player *Player) watchSegments(ctx context.Context, stream *gumbleffmpeg.Stream, path string, provider segments.Provider, videoID string) {
+	found, err := provider.Segments(ctx, videoID)
+	if err != nil {
+		log.Println("Segment lookup for", videoID, "failed:", err)
+		return
+	}
+	if len(found) == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		position := player.Elapsed()
+		for _, segment := range found {
+			if segment.End-segment.Start < minSegment || position < segment.Start || position >= segment.End-time.Second {
+				continue
+			}
+			if player.seekIfCurrent(stream, path, segment.End) {
+				helper.ChanMsg(player.Client, "Skipped <b>"+segment.Category+"</b> segment ("+
+					messages.FormatDuration(segment.Start)+" - "+messages.FormatDuration(segment.End)+")")
+			}
+			return
+		}
+	}
+}
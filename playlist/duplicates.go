@@ -0,0 +1,88 @@
+package playlist
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/iotku/mumzic/youtubedl"
+)
+
+// Policies for adding tracks which are queued already, see List.Duplicates
+const (
+	DuplicatesAllow  = "allow"
+	DuplicatesWarn   = "warn"
+	DuplicatesReject = "reject"
+)
+
+// DuplicatePolicies lists the valid values of List.Duplicates ("" is the same as DuplicatesAllow)
+var DuplicatePolicies = []string{DuplicatesReject, DuplicatesWarn, DuplicatesAllow}
+
+// DuplicateError is returned by AddToQueue and AddNext for a track which is playing or coming up already.
+// With DuplicatesWarn the track is added anyway and Added is set, see IsWarning.
+type DuplicateError struct {
+	Human string
+	Added bool
+}
+
+func (err *DuplicateError) Error() string {
+	if err.Added {
+		return err.Human + " was already queued"
+	}
+	return err.Human + " is already queued"
+}
+
+// IsWarning reports whether err only warns about a duplicate track, which was added anyway
+func IsWarning(err error) bool {
+	var duplicate *DuplicateError
+	return errors.As(err, &duplicate) && duplicate.Added
+}
+
+// trackKey identifies the track at path for finding duplicates: library tracks by their path, so by their ID as well,
+// and URLs in their canonical form
+func trackKey(path string) string {
+	if strings.HasPrefix(path, "http") {
+		return youtubedl.CanonicalURL(path)
+	}
+	return path
+}
+
+// duplicate returns the error for adding the track at path under the duplicate policy, nil if it may be added quietly.
+// list.mu has to be held.
+func (list *List) duplicate(path, human string) *DuplicateError {
+	if list.Duplicates != DuplicatesWarn && list.Duplicates != DuplicatesReject || len(list.Playlist) == 0 {
+		return nil
+	}
+	key := trackKey(path)
+	for _, v := range list.Playlist[list.Position:] {
+		if trackKey(v[0]) == key {
+			return &DuplicateError{Human: human, Added: list.Duplicates == DuplicatesWarn}
+		}
+	}
+	return nil
+}
+
+// Dedupe removes upcoming items which are playing or coming up earlier already, returning how many were removed
+func (list *List) Dedupe() int {
+	list.mu.Lock()
+	defer list.mu.Unlock()
+	if !list.hasNext() {
+		return 0
+	}
+	seen := make(map[string]bool)
+	deduped := append([][]string(nil), list.Playlist[:list.Position]...) // Played items stay for undo and history
+	for _, v := range list.Playlist[list.Position:] {
+		key := trackKey(v[0])
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		deduped = append(deduped, v)
+	}
+
+	removed := len(list.Playlist) - len(deduped)
+	if removed != 0 {
+		list.checkpoint("dedupe")
+		list.Playlist = deduped
+	}
+	return removed
+}
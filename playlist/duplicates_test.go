@@ -0,0 +1,123 @@
+package playlist
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/iotku/mumzic/database/dbtest"
+	"github.com/iotku/mumzic/youtubedl"
+)
+
+func TestDuplicate(t *testing.T) {
+	tests := []struct {
+		policy   string
+		path     string
+		expected error
+	}{
+		{DuplicatesAllow, "/music/a.flac", nil},
+		{"", "/music/a.flac", nil},
+		{DuplicatesWarn, "/music/a.flac", &DuplicateError{Human: "A", Added: true}},
+		{DuplicatesReject, "https://youtu.be/x", &DuplicateError{Human: "A"}},
+		{DuplicatesReject, "/music/b.flac", nil},
+		{DuplicatesReject, "/music/played.flac", nil},
+	}
+
+	for _, tt := range tests {
+		list := List{Duplicates: tt.policy}
+		list.AddTracks([][]string{{"/music/played.flac", "P"}, {"/music/a.flac", "A"}, {"https://www.youtube.com/watch?v=x", "X"}}, "")
+		list.Position = 1
+		got := list.duplicate(tt.path, "A")
+		if (got == nil) != (tt.expected == nil) || got != nil && *got != *tt.expected.(*DuplicateError) {
+			t.Errorf("duplicate(%q) with %q = %v, want %v", tt.path, tt.policy, got, tt.expected)
+		}
+	}
+
+	if !IsWarning(error(&DuplicateError{Added: true})) || IsWarning(&DuplicateError{}) || IsWarning(errors.New("other")) {
+		t.Error("IsWarning only has to be true for duplicates which were added")
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	list := List{}
+	list.AddTracks([][]string{{"a", "A"}, {"b", "B"}, {"https://youtu.be/x", "X"}, {"a", "A"},
+		{"b", "B"}, {"https://www.youtube.com/watch?v=x", "X"}, {"c", "C"}}, "")
+	list.Position = 1
+
+	if removed := list.Dedupe(); removed != 2 {
+		t.Errorf("Dedupe removed %d, want 2", removed)
+	}
+	var paths []string
+	for _, v := range list.Playlist {
+		paths = append(paths, v[0])
+	}
+	if got := len(paths); got != 5 || paths[3] != "a" || paths[4] != "c" {
+		t.Errorf("Playlist after Dedupe = %v, want [a b https://youtu.be/x a c]", paths)
+	}
+	if action, ok := list.Undo(); !ok || action != "dedupe" || list.Size() != 7 {
+		t.Errorf("Undo after Dedupe = %q, %v with %d items, want dedupe with 7", action, ok, list.Size())
+	}
+}
+
+func TestQueuePlaylistDuplicates(t *testing.T) {
+	list := List{Duplicates: DuplicatesReject}
+	list.AddTracks([][]string{{"https://youtu.be/x", "X"}}, "")
+
+	added, duplicates := list.QueuePlaylist([]youtubedl.PlaylistEntry{
+		{URL: "https://www.youtube.com/watch?v=x", Title: "X"},
+		{URL: "https://www.youtube.com/watch?v=y", Title: "Y"},
+	}, "user")
+	if added != 1 || duplicates != 1 || list.Size() != 2 || list.Playlist[1][0] != "https://www.youtube.com/watch?v=y" {
+		t.Errorf("QueuePlaylist added %d, left out %d, playlist %v, want only y added", added, duplicates, list.Playlist)
+	}
+	if action, ok := list.Undo(); !ok || action != "playall" || list.Size() != 1 {
+		t.Errorf("Undo after QueuePlaylist = %q, %v with %d items, want playall with 1", action, ok, list.Size())
+	}
+}
+
+func TestAddTracksDuplicates(t *testing.T) {
+	tracks := [][]string{{"a", "A"}, {"https://youtu.be/x", "X"}, {"a", "A"}, {"https://www.youtube.com/watch?v=x", "X"}, {"b", "B"}}
+	tests := []struct {
+		policy     string
+		duplicates int
+		size       int
+	}{
+		{DuplicatesAllow, 0, 5},
+		{DuplicatesWarn, 0, 5},
+		{DuplicatesReject, 2, 3},
+	}
+
+	for _, tt := range tests {
+		list := List{Duplicates: tt.policy}
+		if duplicates := list.AddTracks(tracks, "user"); duplicates != tt.duplicates || list.Size() != tt.size {
+			t.Errorf("AddTracks with %q left out %d, queuing %d, want %d queuing %d", tt.policy, duplicates, list.Size(), tt.duplicates, tt.size)
+		}
+		if duplicates := list.Replace(tracks[1:], "user"); duplicates != tt.duplicates/2 {
+			t.Errorf("Replace with %q left out %d, want %d", tt.policy, duplicates, tt.duplicates/2)
+		}
+	}
+}
+
+func TestQueueIDDuplicates(t *testing.T) {
+	dbtest.UseMediaDB(t, []dbtest.Track{{Artist: "Artist", Title: "A", Path: "/music/a.flac"}}, false)
+
+	tests := []struct {
+		policy  string
+		size    int
+		warning bool
+	}{
+		{DuplicatesAllow, 2, false},
+		{DuplicatesWarn, 2, true},
+		{DuplicatesReject, 1, false},
+	}
+	for _, tt := range tests {
+		list := List{Duplicates: tt.policy}
+		list.AddTracks([][]string{{"/music/a.flac", "Artist - A"}}, "")
+		human, err := list.QueueID(1, "user")
+		if human != "Artist - A" || list.Size() != tt.size {
+			t.Errorf("QueueID with %q = %q queuing %d, want %q queuing %d", tt.policy, human, list.Size(), "Artist - A", tt.size)
+		}
+		if rejected := tt.size == 1; (err != nil) != (tt.warning || rejected) || IsWarning(err) != tt.warning {
+			t.Errorf("QueueID with %q err = %v", tt.policy, err)
+		}
+	}
+}
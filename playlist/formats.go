@@ -0,0 +1,253 @@
+package playlist
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/iotku/mumzic/search"
+	"github.com/iotku/mumzic/youtubedl"
+)
+
+// FilesDirectory holds playlist files imported and exported with chat commands
+const FilesDirectory = Directory + "files/"
+
+// Formats lists the supported playlist file formats, by file extension
+var Formats = []string{"m3u", "m3u8", "pls", "xspf"}
+
+// Entry is a track of a playlist file
+type Entry struct {
+	Location string // Local path or URL
+	Title    string // May be empty
+}
+
+// FormatOf returns the format of a playlist file from its extension
+func FormatOf(name string) (string, error) {
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(name)), ".")
+	for _, v := range Formats {
+		if v == format {
+			return format, nil
+		}
+	}
+	return "", errors.New("unsupported playlist format, use one of: " + strings.Join(Formats, ", "))
+}
+
+// Decode reads the entries of a playlist file in format
+func Decode(r io.Reader, format string) ([]Entry, error) {
+	switch format {
+	case "m3u", "m3u8":
+		return decodeM3U(r)
+	case "pls":
+		return decodePLS(r)
+	case "xspf":
+		return decodeXSPF(r)
+	}
+	return nil, errors.New("unsupported playlist format: " + format)
+}
+
+// Encode writes tracks (path and human title pairs, like List.Playlist) as a playlist file in format
+func Encode(w io.Writer, format string, tracks [][]string) error {
+	switch format {
+	case "m3u", "m3u8":
+		return encodeM3U(w, tracks)
+	case "pls":
+		return encodePLS(w, tracks)
+	case "xspf":
+		return encodeXSPF(w, tracks)
+	}
+	return errors.New("unsupported playlist format: " + format)
+}
+
+// Import reads the playlist file at path into tracks for the queue. Local files are mapped to library
+// tracks (relative paths are relative to the playlist file) and URLs have to pass the whitelist.
+// Entries which can't be played are returned in skipped.
+func Import(path string) (tracks [][]string, skipped []string, err error) {
+	format, err := FormatOf(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Open(path) // #nosec G304 -- path is given by the bot owner or restricted to FilesDirectory
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	entries, err := Decode(f, format)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, entry := range entries {
+		location := entry.Location
+		if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+			if !youtubedl.IsWhiteListedURL(location) {
+				skipped = append(skipped, location)
+				continue
+			}
+			human := entry.Title
+			if human == "" {
+				human = location
+			}
+			tracks = append(tracks, []string{location, human})
+			continue
+		}
+
+		if u, err := url.Parse(location); err == nil && u.Scheme == "file" {
+			location = u.Path
+			if location == "" { // file:relative/path
+				location = u.Opaque
+			}
+		}
+		id := search.GetTrackIDByPath(location) // Library paths may be relative themselves
+		if id == 0 && !filepath.IsAbs(location) {
+			id = search.GetTrackIDByPath(filepath.Join(filepath.Dir(path), location))
+		}
+		if id == 0 {
+			skipped = append(skipped, entry.Location)
+			continue
+		}
+		human, trackPath := search.GetTrackById(id)
+		tracks = append(tracks, []string{trackPath, human})
+	}
+	return tracks, skipped, nil
+}
+
+// Export writes tracks to a playlist file at path, the format is picked by its extension
+func Export(path string, tracks [][]string) error {
+	format, err := FormatOf(path)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600) // #nosec G304 -- see Import
+	if err != nil {
+		return err
+	}
+	if err := Encode(f, format, tracks); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func decodeM3U(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	var title string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff")) // Byte order mark of UTF-8 files
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#EXTINF:"):
+			if _, t, ok := strings.Cut(line, ","); ok {
+				title = strings.TrimSpace(t)
+			}
+		case strings.HasPrefix(line, "#"):
+		default:
+			entries = append(entries, Entry{Location: line, Title: title})
+			title = ""
+		}
+	}
+	return entries, scanner.Err()
+}
+
+func encodeM3U(w io.Writer, tracks [][]string) error {
+	b := bufio.NewWriter(w)
+	b.WriteString("#EXTM3U\n")
+	for _, v := range tracks {
+		fmt.Fprintf(b, "#EXTINF:-1,%s\n%s\n", oneLine(v[1]), v[0])
+	}
+	return b.Flush()
+}
+
+func decodePLS(r io.Reader) ([]Entry, error) {
+	files := make(map[int]string)
+	titles := make(map[int]string)
+	var numbers []int
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		if n, err := strconv.Atoi(strings.TrimPrefix(key, "file")); strings.HasPrefix(key, "file") && err == nil {
+			files[n] = strings.TrimSpace(value)
+			numbers = append(numbers, n)
+		} else if n, err := strconv.Atoi(strings.TrimPrefix(key, "title")); strings.HasPrefix(key, "title") && err == nil {
+			titles[n] = strings.TrimSpace(value)
+		}
+	}
+
+	var entries []Entry
+	for _, n := range numbers { // In file order, the numbers aren't always in sequence
+		entries = append(entries, Entry{Location: files[n], Title: titles[n]})
+	}
+	return entries, scanner.Err()
+}
+
+func encodePLS(w io.Writer, tracks [][]string) error {
+	b := bufio.NewWriter(w)
+	b.WriteString("[playlist]\n")
+	for i, v := range tracks {
+		fmt.Fprintf(b, "File%d=%s\nTitle%d=%s\nLength%d=-1\n", i+1, v[0], i+1, oneLine(v[1]), i+1)
+	}
+	fmt.Fprintf(b, "NumberOfEntries=%d\nVersion=2\n", len(tracks))
+	return b.Flush()
+}
+
+type xspfPlaylist struct {
+	XMLName xml.Name    `xml:"http://xspf.org/ns/0/ playlist"`
+	Version string      `xml:"version,attr"`
+	Tracks  []xspfTrack `xml:"trackList>track"`
+}
+
+type xspfTrack struct {
+	Location string `xml:"location"`
+	Title    string `xml:"title,omitempty"`
+}
+
+func decodeXSPF(r io.Reader) ([]Entry, error) {
+	var playlist xspfPlaylist
+	if err := xml.NewDecoder(r).Decode(&playlist); err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(playlist.Tracks))
+	for _, v := range playlist.Tracks {
+		entries = append(entries, Entry{Location: strings.TrimSpace(v.Location), Title: strings.TrimSpace(v.Title)})
+	}
+	return entries, nil
+}
+
+// encodeXSPF writes local paths as file:// URIs, XSPF locations have to be URIs
+func encodeXSPF(w io.Writer, tracks [][]string) error {
+	playlist := xspfPlaylist{Version: "1"}
+	for _, v := range tracks {
+		location := v[0]
+		if !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+			location = (&url.URL{Scheme: "file", Path: location}).String()
+		}
+		playlist.Tracks = append(playlist.Tracks, xspfTrack{Location: location, Title: v[1]})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(playlist); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// oneLine keeps titles from breaking the line based formats
+func oneLine(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
@@ -0,0 +1,63 @@
+package playlist
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFormatsRoundTrip(t *testing.T) {
+	tracks := [][]string{
+		{"/music/Artist/Album/01 Song.flac", "Artist - Song"},
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ", "Video & <Title>"},
+	}
+	expected := []Entry{
+		{"/music/Artist/Album/01 Song.flac", "Artist - Song"},
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ", "Video & <Title>"},
+	}
+
+	for _, format := range Formats {
+		var buf bytes.Buffer
+		if err := Encode(&buf, format, tracks); err != nil {
+			t.Errorf("Encode(%s) failed: %v", format, err)
+			continue
+		}
+		entries, err := Decode(&buf, format)
+		if err != nil {
+			t.Errorf("Decode(%s) failed: %v", format, err)
+			continue
+		}
+		if format == "xspf" { // Local paths are stored as file:// URIs
+			expected[0].Location = "file:///music/Artist/Album/01%20Song.flac"
+		}
+		if !reflect.DeepEqual(entries, expected) {
+			t.Errorf("%s round trip = %v, want %v", format, entries, expected)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		format   string
+		data     string
+		expected []Entry
+	}{
+		{"m3u", "song.mp3\n\n# comment\nhttps://example.com/a\n", []Entry{{"song.mp3", ""}, {"https://example.com/a", ""}}},
+		{"m3u8", "\ufeff#EXTM3U\n#EXTINF:123,Artist - Title\nsong.mp3\n", []Entry{{"song.mp3", "Artist - Title"}}},
+		{"pls", "[playlist]\nFile2=b.mp3\nTitle1=A\nFile1=a.mp3\nNumberOfEntries=2\n", []Entry{{"b.mp3", ""}, {"a.mp3", "A"}}},
+		{"xspf", `<playlist version="1" xmlns="http://xspf.org/ns/0/"><trackList><track><location>file:///a.ogg</location></track></trackList></playlist>`,
+			[]Entry{{"file:///a.ogg", ""}}},
+	}
+
+	for _, tt := range tests {
+		entries, err := Decode(strings.NewReader(tt.data), tt.format)
+		if err != nil {
+			t.Errorf("Decode(%s) failed: %v", tt.format, err)
+			continue
+		}
+		if !reflect.DeepEqual(entries, tt.expected) {
+			t.Errorf("Decode(%s) = %v, want %v", tt.format, entries, tt.expected)
+		}
+	}
+}
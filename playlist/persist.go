@@ -0,0 +1,107 @@
+package playlist
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Saved is the state of a queue kept across restarts and crashes, see List.Snapshot
+type Saved struct {
+	Queue   [][]string    `json:"queue"`   // Items from the current one on
+	Offset  time.Duration `json:"offset"`  // Position within the current item
+	Playing bool          `json:"playing"` // Whether the current item was playing
+}
+
+// Snapshot returns the queue from the current item on, with how far into it playback is
+func (list *List) Snapshot(offset time.Duration, playing bool) Saved {
+	list.mu.RLock()
+	defer list.mu.RUnlock()
+	saved := Saved{Queue: [][]string{}, Offset: offset, Playing: playing}
+	if len(list.Playlist) != 0 {
+		for _, v := range list.Playlist[list.Position:] {
+			saved.Queue = append(saved.Queue, append([]string(nil), v...))
+		}
+	}
+	return saved
+}
+
+// Restore replaces the playlist with a saved queue, starting at its first item
+func (list *List) Restore(saved Saved) {
+	list.mu.Lock()
+	defer list.mu.Unlock()
+	list.Playlist = saved.Queue
+	list.Position = 0
+}
+
+// Save writes the queue to Directory for hostname. The file is replaced atomically, so a crash while saving
+// leaves the previous queue intact.
+func (saved Saved) Save(hostname string) error {
+	output, err := json.Marshal(saved)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(Directory, 0700); err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(Directory, hostname), output)
+}
+
+// LoadSaved reads the queue saved for hostname, the zero Saved if there is none.
+// Files of older versions, which are a plain list of items, are read as well.
+func LoadSaved(hostname string) (Saved, error) {
+	var saved Saved
+	file, err := os.ReadFile(filepath.Join(Directory, hostname)) //#nosec G304 - hostname considered trusted source
+	if errors.Is(err, os.ErrNotExist) {
+		return saved, nil
+	} else if err != nil {
+		return saved, err
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(file), []byte("[")) {
+		err = json.Unmarshal(file, &saved.Queue)
+	} else {
+		err = json.Unmarshal(file, &saved)
+	}
+	if err != nil {
+		return Saved{}, err
+	}
+	for _, v := range saved.Queue {
+		if len(v) < 2 {
+			return Saved{}, errors.New("invalid queue item in " + hostname)
+		}
+	}
+	return saved, nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path, syncs it and renames it over path
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // #nosec G104 -- fails once renamed
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return err
+	}
+
+	if dir, err := os.Open(filepath.Dir(path)); err == nil { // Persist the rename itself
+		dir.Sync() // #nosec G104 -- not supported everywhere, the rename happened either way
+		dir.Close()
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package playlist
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// chdirTemp runs the test in a temporary directory, Directory being relative
+func chdirTemp(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	chdirTemp(t)
+
+	list := List{}
+	list.AddTracks([][]string{{"/music/a.flac", "A"}, {"https://youtu.be/x", "X"}, {"/music/b.flac", "B"}}, "user")
+	list.Position = 1
+	if err := list.Snapshot(90*time.Second, true).Save("example.com"); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	saved, err := LoadSaved("example.com")
+	if err != nil {
+		t.Fatalf("LoadSaved failed: %v", err)
+	}
+	expected := Saved{Queue: [][]string{{"https://youtu.be/x", "X", "user"}, {"/music/b.flac", "B", "user"}}, Offset: 90 * time.Second, Playing: true}
+	if !reflect.DeepEqual(saved, expected) {
+		t.Errorf("LoadSaved = %+v, want %+v", saved, expected)
+	}
+	if entries, _ := os.ReadDir(Directory); len(entries) != 1 {
+		t.Errorf("Directory has %d files after saving, want only the queue", len(entries))
+	}
+
+	if saved, err := LoadSaved("missing.example.com"); err != nil || len(saved.Queue) != 0 {
+		t.Errorf("LoadSaved without a file = %+v, %v, want an empty queue", saved, err)
+	}
+}
+
+func TestLoadOldFormat(t *testing.T) {
+	chdirTemp(t)
+	if err := os.MkdirAll(Directory, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(Directory, "old"), []byte(`[["/music/a.flac","A"]]`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(Directory, "broken"), []byte(`{"queue": [["/music/a.flac"`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := LoadSaved("old")
+	if err != nil || !reflect.DeepEqual(saved, Saved{Queue: [][]string{{"/music/a.flac", "A"}}}) {
+		t.Errorf("LoadSaved of the old format = %+v, %v", saved, err)
+	}
+	if _, err := LoadSaved("broken"); err == nil {
+		t.Error("LoadSaved of a broken file succeeded, want error")
+	}
+}
@@ -1,83 +1,78 @@
 package playlist
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
-	"log"
-	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/iotku/mumzic/helper"
+	"github.com/iotku/mumzic/resolver"
 	"github.com/iotku/mumzic/search"
 	"github.com/iotku/mumzic/youtubedl"
 )
 
 const Directory = "playlists/" // Directory for saving/loading playlists
 
-// List contains a 2D slice of "Human Friendly" titles and raw paths as well as its position along the playlist
+// List contains a 2D slice of "Human Friendly" titles and raw paths as well as its position along the playlist.
+// Items are {path, human} or {path, human, requester}, the requester being the name of the user who queued it.
+// The methods of List may be called concurrently, Playlist and Position shouldn't be used directly outside of it.
 type List struct {
-	Playlist [][]string
-	Position int
-}
-
-func (list *List) Save(hostname string) {
-	var saveList [][]string
-	for i := list.Position; i < len(list.Playlist); i++ {
-		saveList = append(saveList, list.Playlist[i])
-	}
-
-	if saveList == nil {
-		return
-	}
-
-	output, err := json.Marshal(saveList)
-	if err != nil {
-		log.Fatalln("failed to marshal playlist json")
-	}
-
-	if _, err := os.Stat(Directory); os.IsNotExist(err) {
-		if err = os.Mkdir(Directory, 0700); err != nil {
-			log.Fatalln("failed to create"+Directory+"directory:", err)
-		}
-	}
-	if fileInfo, _ := os.Stat(Directory); !fileInfo.IsDir() {
-		log.Fatalln("Playlist path, ", Directory, "is not a directory.")
-	}
-
-	if err := os.WriteFile(Directory+hostname, output, 0600); err != nil {
-		log.Fatalln("Failed to write playlist file:", err.Error())
-	}
-}
+	Playlist    [][]string
+	Position    int
+	MaxDuration time.Duration // Longest URL track which may be added, 0 for no limit
+	Duplicates  string        // Policy for adding tracks which are queued already, see DuplicatePolicies
 
-func (list *List) Load(hostname string) {
-	if _, err := os.Open(Directory + hostname); //#nosec G304 - hostname considered rusted source
-	err == nil {
-		file, err := os.ReadFile(Directory + hostname) //#nosec G304 - hostname considered rusted source
-		if err != nil {
-			log.Fatalln(err.Error())
-		}
-		var pList [][]string
-		err = json.Unmarshal(file, &pList)
-		if err != nil {
-			log.Fatalln("json Unmarshal failed", err.Error())
-		}
-		log.Println("Applying previous playlist file.")
-		list.Playlist = pList
-	}
+	undo []undoEntry // Checkpoints of destructive changes, the latest last (see Undo)
+	mu   sync.RWMutex
 }
 
 // GetCurrentPath gets the raw path for the current item in the playlist
 func (list *List) GetCurrentPath() string {
+	list.mu.RLock()
+	defer list.mu.RUnlock()
 	return list.Playlist[list.Position][0]
 }
 
 // GetCurrentHuman gets the "Human Friendly" title for the current item in the playlist
 func (list *List) GetCurrentHuman() string {
+	list.mu.RLock()
+	defer list.mu.RUnlock()
 	return list.Playlist[list.Position][1]
 }
 
+// GetCurrentRequester gets the name of the user who queued the current item, "" if unknown (e.g. radio mode)
+func (list *List) GetCurrentRequester() string {
+	list.mu.RLock()
+	defer list.mu.RUnlock()
+	if item := list.Playlist[list.Position]; len(item) > 2 {
+		return item[2]
+	}
+	return ""
+}
+
+// GetPosition returns the index of the current item in the playlist
+func (list *List) GetPosition() int {
+	list.mu.RLock()
+	defer list.mu.RUnlock()
+	return list.Position
+}
+
+// GetNextPath gets the raw path of the item after the current one, "" if there is none
+func (list *List) GetNextPath() string {
+	list.mu.RLock()
+	defer list.mu.RUnlock()
+	if !list.hasNext() {
+		return ""
+	}
+	return list.Playlist[list.Position+1][0]
+}
+
 func (list *List) GetNextHuman() string {
+	list.mu.RLock()
+	defer list.mu.RUnlock()
 	if len(list.Playlist) == 0 {
 		return ""
 	} else if len(list.Playlist) == list.Position+1 {
@@ -89,9 +84,11 @@ func (list *List) GetNextHuman() string {
 // GetList returns a list of items from the current to the end of the playlist
 // Note: Older items aren't removed immediately however aren't guaranteed to remain forever.
 func (list *List) GetList(max int) []string {
+	list.mu.RLock()
+	defer list.mu.RUnlock()
 	var trackList []string
 	for i := list.Position; i < list.Position+max; i++ {
-		if list.Position+max > list.Size() {
+		if list.Position+max > len(list.Playlist) {
 			return trackList
 		}
 		trackList = append(trackList, list.Playlist[i][1])
@@ -102,88 +99,123 @@ func (list *List) GetList(max int) []string {
 
 // HasNext returns true if there is another item remaining in the playlist
 func (list *List) HasNext() bool {
+	list.mu.RLock()
+	defer list.mu.RUnlock()
+	return list.hasNext()
+}
+
+func (list *List) hasNext() bool {
 	return len(list.Playlist) > list.Position+1
 }
 
 // Next shifts the playlist position forward by one if there is at least one more item in the playlist remaining
 func (list *List) Next() string {
-	if !list.HasNext() {
+	list.mu.Lock()
+	defer list.mu.Unlock()
+	if !list.hasNext() {
 		return ""
 	}
 	list.Position++
-	return list.GetCurrentPath()
+	return list.Playlist[list.Position][0]
 }
 
 // Skip moves the position by amount, generally this should be called by a playback.Player
 func (list *List) Skip(amount int) string {
-	if list.Size()+amount < 0 || !list.HasNext() {
+	list.mu.Lock()
+	defer list.mu.Unlock()
+	if len(list.Playlist)+amount < 0 || !list.hasNext() {
 		return ""
 	}
 
-	if list.Position+amount >= list.Size() {
+	if list.Position+amount >= len(list.Playlist) {
 		amount = 1 // only skip one track
 	}
+	list.checkpoint("skip")
 	list.Position += amount
-	return list.GetCurrentPath()
+	return list.Playlist[list.Position][0]
 }
 
 // Size returns an int of how many items the playlist contains
 func (list *List) Size() int {
+	list.mu.RLock()
+	defer list.mu.RUnlock()
 	return len(list.Playlist)
 }
 
 // IsEmpty returns whether the playlist contains any elements.
 func (list *List) IsEmpty() bool {
+	list.mu.RLock()
+	defer list.mu.RUnlock()
 	return len(list.Playlist) == 0
 }
 
 // AddToQueue ads either a filesystem ID or internet URL onto the Playlist queue. On success, it returns a human friendly
 // title and err is nil. On failure (ID not found or not whitelisted URL) returns empty string "" and a respective error.
-func (list *List) AddToQueue(path string) (string, error) {
-	human, path, err := getHumanAndPath(path) // NOTE: we check for whitelist urls here
+// Tracks which are queued already return a *DuplicateError, see Duplicates.
+func (list *List) AddToQueue(path, requester string) (string, error) {
+	human, path, err := list.getHumanAndPath(path) // NOTE: we check for whitelist urls here
 	if err != nil {
 		return "", err
 	} else if path == "" {
 		return "", errors.New("nothing added. (Invalid ID?)")
 	}
 
+	list.mu.Lock()
+	defer list.mu.Unlock()
+	duplicate := list.duplicate(path, human)
+	if duplicate != nil && !duplicate.Added {
+		return "", duplicate
+	}
+
 	if strings.HasPrefix(path, "http") {
-		list.queueYT(path, human)
+		list.queueYT(path, human, requester)
 	} else {
-		list.pAdd(path, human)
+		list.pAdd(path, human, requester)
 	}
 
+	if duplicate != nil {
+		return human, duplicate
+	}
 	return human, nil
 }
 
-// AddNext adds a song to play directly after the current song in the Playlist
-func (list *List) AddNext(arg string) error {
-	human, path, err := getHumanAndPath(arg)
+// AddNext adds a song to play directly after the current song in the Playlist, duplicates are handled like in AddToQueue
+func (list *List) AddNext(arg, requester string) error {
+	human, path, err := list.getHumanAndPath(arg)
 	if err != nil {
 		return err
 	}
-	if list.Count() <= 1 || !list.HasNext() {
-		list.pAdd(path, human)
-		return nil
+
+	list.mu.Lock()
+	defer list.mu.Unlock()
+	duplicate := list.duplicate(path, human)
+	if duplicate != nil && !duplicate.Added {
+		return duplicate
+	}
+	if duplicate != nil {
+		err = duplicate
+	}
+	if list.count() <= 1 || !list.hasNext() {
+		list.pAdd(path, human, requester)
+		return err
 	}
 
 	var newList [][]string
 	newList = append(newList, list.Playlist[list.Position])
-	newList = append(newList, []string{path, human})
+	newList = append(newList, []string{path, human, requester})
 	newList = append(newList, list.Playlist[list.Position+1:]...)
 
 	// Copy New Playlist
 	list.Playlist = newList
 	list.Position = 0
 
-	return nil
+	return err
 }
 
-func getHumanAndPath(arg string) (human, path string, err error) {
+func (list *List) getHumanAndPath(arg string) (human, path string, err error) {
 	path = helper.StripHTMLTags(arg) // TODO: Might be redundant now that we Strip message beforehand
 	if strings.HasPrefix(path, "http") && youtubedl.IsWhiteListedURL(path) == true {
-		human, err = youtubedl.GetYtDLTitle(path)
-		return
+		return list.resolveHuman(path)
 	} else if strings.HasPrefix(path, "http") {
 		return "", "", errors.New("URL Doesn't meet whitelist")
 	}
@@ -197,35 +229,163 @@ func getHumanAndPath(arg string) (human, path string, err error) {
 	}
 
 	// If not a valid ID, try YouTube search
-	path, err = youtubedl.SearchYouTube(arg)
+	path, err = youtubedl.SearchYouTube(context.Background(), arg)
 	if err == nil {
-		human, _ = youtubedl.GetYtDLTitle(path)
-		return
+		return list.resolveHuman(path)
+	} else if errors.Is(err, youtubedl.ErrTimeout) {
+		return "", "", err
 	}
 
 	return "", "", errors.New("id not found and search failed")
 }
 
-func (list *List) pAdd(path, human string) {
-	list.Playlist = append(list.Playlist, []string{path, human})
+// resolveHuman returns the title of a URL (see package resolver), rejecting tracks longer than MaxDuration.
+// Live streams have no length, so they are never rejected.
+func (list *List) resolveHuman(url string) (human, path string, err error) {
+	track, err := resolver.Resolve(context.Background(), url)
+	if err != nil {
+		return "", "", err
+	}
+	if list.MaxDuration > 0 && !track.Live && track.Duration > list.MaxDuration {
+		return "", "", errors.New("track is longer than the " + list.MaxDuration.String() + " limit")
+	}
+	return track.Title, url, nil
+}
+
+func (list *List) pAdd(path, human, requester string) {
+	list.Playlist = append(list.Playlist, []string{path, human, requester})
 }
 
-func (list *List) QueueID(trackID int) (human string) {
+// QueueID adds the library track with trackID, returning its title ("" if there is no such track).
+// Duplicates are handled like in AddToQueue.
+func (list *List) QueueID(trackID int, requester string) (human string, err error) {
 	human, path := search.GetTrackById(trackID)
 	if path == "" {
-		return ""
+		return "", nil
+	}
+	list.mu.Lock()
+	defer list.mu.Unlock()
+	duplicate := list.duplicate(path, human)
+	if duplicate != nil && !duplicate.Added {
+		return human, duplicate
+	}
+	list.pAdd(path, human, requester)
+
+	if duplicate != nil {
+		return human, duplicate
+	}
+	return human, nil
+}
+
+// QueuePlaylist adds the entries of an expanded playlist to the queue, which can be undone as a whole, returning how many
+// were added and how many were left out for being queued already (see Duplicates).
+// Entries without a title are queued under their URL and titled in the background, one at a time.
+func (list *List) QueuePlaylist(entries []youtubedl.PlaylistEntry, requester string) (added, duplicates int) {
+	list.mu.Lock()
+	defer list.mu.Unlock()
+	list.checkpoint("playall")
+
+	var untitled []string
+	for _, entry := range entries {
+		if list.MaxDuration > 0 && time.Duration(entry.Duration*float64(time.Second)) > list.MaxDuration {
+			continue
+		}
+		human := entry.Title
+		if human == "" {
+			human = entry.URL
+		}
+		if duplicate := list.duplicate(entry.URL, human); duplicate != nil && !duplicate.Added {
+			duplicates++
+			continue
+		}
+		if human == entry.URL {
+			untitled = append(untitled, entry.URL)
+		}
+		list.queueYT(entry.URL, human, requester)
+		added++
 	}
-	list.pAdd(path, human)
 
-	return human
+	if len(untitled) != 0 {
+		go func() {
+			for _, url := range untitled {
+				if title, err := youtubedl.GetYtDLTitle(context.Background(), url); err == nil {
+					list.setHuman(url, title)
+				}
+			}
+		}()
+	}
+	return added, duplicates
 }
 
-func (list *List) queueYT(url, human string) bool {
-	list.pAdd(url, human)
+// setHuman replaces the placeholder title (the URL itself) of queued entries for url
+func (list *List) setHuman(url, human string) {
+	list.mu.Lock()
+	defer list.mu.Unlock()
+	for i, v := range list.Playlist {
+		if v[0] == url && v[1] == url {
+			item := append([]string(nil), v...) // Items may be shared with undo checkpoints and snapshots
+			item[1] = human
+			list.Playlist[i] = item
+		}
+	}
+}
+
+func (list *List) queueYT(url, human, requester string) bool {
+	list.pAdd(url, human, requester)
 	return true // TODO Check with API if video is valid for youtube links
 }
 
 // Count is the amount of songs enqueued on the playlist
 func (list *List) Count() int {
-	return list.Size() - list.Position
+	list.mu.RLock()
+	defer list.mu.RUnlock()
+	return list.count()
+}
+
+func (list *List) count() int {
+	return len(list.Playlist) - list.Position
+}
+
+// Upcoming returns a copy of the current and following items as path and human title pairs
+func (list *List) Upcoming() [][]string {
+	list.mu.RLock()
+	defer list.mu.RUnlock()
+	if len(list.Playlist) == 0 {
+		return nil
+	}
+	upcoming := make([][]string, 0, list.count())
+	for _, v := range list.Playlist[list.Position:] {
+		upcoming = append(upcoming, []string{v[0], v[1]})
+	}
+	return upcoming
+}
+
+// Replace swaps the whole playlist for tracks (path and human title pairs) queued by requester, starting at the first one.
+// Duplicates are left out like in AddTracks.
+func (list *List) Replace(tracks [][]string, requester string) (duplicates int) {
+	list.mu.Lock()
+	defer list.mu.Unlock()
+	list.checkpoint("replace")
+	list.Playlist = nil
+	list.Position = 0
+	return list.addTracks(tracks, requester)
+}
+
+// AddTracks appends tracks (path and human title pairs) queued by requester to the end of the playlist. Tracks which are
+// queued already are handled like in AddToQueue, though rejected ones are simply left out and counted in duplicates.
+func (list *List) AddTracks(tracks [][]string, requester string) (duplicates int) {
+	list.mu.Lock()
+	defer list.mu.Unlock()
+	return list.addTracks(tracks, requester)
+}
+
+func (list *List) addTracks(tracks [][]string, requester string) (duplicates int) {
+	for _, v := range tracks {
+		if duplicate := list.duplicate(v[0], v[1]); duplicate != nil && !duplicate.Added {
+			duplicates++
+			continue
+		}
+		list.pAdd(v[0], v[1], requester)
+	}
+	return duplicates
 }
@@ -0,0 +1,56 @@
+package playlist
+
+// maxUndo is how many destructive changes of a playlist can be undone
+const maxUndo = 5
+
+// undoEntry is a playlist as it was before a destructive change
+type undoEntry struct {
+	action   string
+	playlist [][]string
+	position int
+}
+
+// Checkpoint records the playlist before a destructive change described by action (e.g. "skip"), so Undo can
+// restore it. Only the last maxUndo checkpoints are kept.
+func (list *List) Checkpoint(action string) {
+	list.mu.Lock()
+	defer list.mu.Unlock()
+	list.checkpoint(action)
+}
+
+func (list *List) checkpoint(action string) {
+	list.undo = append(list.undo, undoEntry{
+		action:   action,
+		playlist: append([][]string(nil), list.Playlist...),
+		position: list.Position,
+	})
+	if len(list.undo) > maxUndo {
+		list.undo = append(list.undo[:0], list.undo[len(list.undo)-maxUndo:]...)
+	}
+}
+
+// Undo restores the playlist and position from the last checkpoint, returning its action, or false if there is none
+func (list *List) Undo() (action string, ok bool) {
+	list.mu.Lock()
+	defer list.mu.Unlock()
+	if len(list.undo) == 0 {
+		return "", false
+	}
+	last := list.undo[len(list.undo)-1]
+	list.undo = list.undo[:len(list.undo)-1]
+	list.Playlist, list.Position = last.playlist, last.position
+	return last.action, true
+}
+
+// Clear removes every item after the current one
+func (list *List) Clear() int {
+	list.mu.Lock()
+	defer list.mu.Unlock()
+	if !list.hasNext() {
+		return 0
+	}
+	list.checkpoint("clear")
+	removed := len(list.Playlist) - list.Position - 1
+	list.Playlist = list.Playlist[: list.Position+1 : list.Position+1]
+	return removed
+}
@@ -0,0 +1,54 @@
+package playlist
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUndo(t *testing.T) {
+	list := List{}
+	list.AddTracks([][]string{{"a", "A"}, {"b", "B"}, {"c", "C"}, {"d", "D"}}, "user")
+	original := append([][]string(nil), list.Playlist...)
+
+	list.Skip(2)
+	if removed := list.Clear(); removed != 1 {
+		t.Errorf("Clear removed %d, want 1", removed)
+	}
+	list.Replace([][]string{{"e", "E"}}, "user")
+
+	for _, expected := range []struct {
+		action   string
+		size     int
+		position int
+	}{
+		{"replace", 3, 2},
+		{"clear", 4, 2},
+		{"skip", 4, 0},
+	} {
+		action, ok := list.Undo()
+		if !ok || action != expected.action || list.Size() != expected.size || list.Position != expected.position {
+			t.Errorf("Undo = %q, %v with %d items at %d, want %q with %d at %d",
+				action, ok, list.Size(), list.Position, expected.action, expected.size, expected.position)
+		}
+	}
+	if !reflect.DeepEqual(list.Playlist, original) {
+		t.Errorf("Playlist after undoing everything = %v, want %v", list.Playlist, original)
+	}
+	if _, ok := list.Undo(); ok {
+		t.Error("Undo with an empty journal succeeded")
+	}
+}
+
+func TestUndoLimit(t *testing.T) {
+	list := List{}
+	for i := 0; i < maxUndo+3; i++ {
+		list.Checkpoint("test")
+	}
+	var undone int
+	for _, ok := list.Undo(); ok; _, ok = list.Undo() {
+		undone++
+	}
+	if undone != maxUndo {
+		t.Errorf("Undid %d checkpoints, want %d", undone, maxUndo)
+	}
+}
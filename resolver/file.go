@@ -0,0 +1,90 @@
+package resolver
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// How long ffprobe may take to read the header of a remote file
+const probeTimeout = 15 * time.Second
+
+// File handles direct links to audio files, which ffmpeg reads (and seeks in) by itself
+type File struct{}
+
+func (File) Name() string { return "file" }
+
+// Claims URLs ending in an audio file extension
+func (File) Claims(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	switch strings.ToLower(path.Ext(u.Path)) {
+	case ".mp3", ".ogg", ".oga", ".opus", ".aac", ".m4a", ".flac", ".wav":
+		return true
+	}
+	return false
+}
+
+// Resolve reads the length and tags of the file with ffprobe.
+// Files without a length are left to HTTPStream, as they are most likely stations.
+func (File) Resolve(ctx context.Context, rawURL string) (Track, error) {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	// #nosec G204 -- rawURL passed the whitelist
+	output, err := exec.CommandContext(ctx, "ffprobe", "-v", "error", "-show_entries",
+		"format=duration:format_tags=title,artist", "-of", "json", "-i", rawURL).Output()
+	if err != nil {
+		return Track{}, err
+	}
+
+	var probe struct {
+		Format struct {
+			Duration string            `json:"duration"`
+			Tags     map[string]string `json:"tags"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal(output, &probe); err != nil {
+		return Track{}, err
+	}
+	seconds, err := strconv.ParseFloat(probe.Format.Duration, 64)
+	if err != nil || seconds <= 0 {
+		return Track{}, ErrNotClaimed
+	}
+
+	track := Track{URL: rawURL, Duration: time.Duration(seconds * float64(time.Second))}
+	for key, value := range probe.Format.Tags { // Tag names are upper case in some containers
+		switch strings.ToLower(key) {
+		case "title":
+			track.Title = value
+		case "artist":
+			track.Uploader = value
+		}
+	}
+	if track.Title == "" {
+		track.Title = fileName(rawURL)
+	}
+	return track, nil
+}
+
+func (f File) Open(ctx context.Context, rawURL string) (Source, error) {
+	track, err := f.Resolve(ctx, rawURL)
+	if err != nil {
+		return Source{}, err
+	}
+	return Source{Track: track, Path: rawURL}, nil
+}
+
+func fileName(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return path.Base(u.Path)
+}
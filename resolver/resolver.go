@@ -0,0 +1,100 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"time"
+)
+
+// ErrNotClaimed is returned by a resolver which turns out not to handle a URL after all,
+// the URL is then handed to the next resolver
+var ErrNotClaimed = errors.New("URL not handled by this resolver")
+
+// ErrNoResolver is returned when no resolver handles a URL
+var ErrNoResolver = errors.New("no resolver for URL")
+
+// Track is what a resolver knows about a URL
+type Track struct {
+	URL      string
+	Title    string
+	Uploader string
+	Duration time.Duration // 0 if unknown or live
+	Live     bool          // No fixed length, so it can't be seeked
+	Stream   bool          // Plain audio read by the bot itself, rather than a page for yt-dlp
+	Resolver string        // Name of the resolver which handled the URL
+	Chapters []Chapter
+	VideoID  string // ID of YouTube videos, e.g. for looking up segments to skip
+}
+
+// Chapter is a named part of a track, such as a song of a mix
+type Chapter struct {
+	Title      string
+	Start, End time.Duration
+}
+
+// Source is the audio of a track, exactly one of Path, Cmd and Reader is set (like audio.Input)
+type Source struct {
+	Track
+	Path   string        // Anything ffmpeg can open by itself
+	Cmd    *exec.Cmd     // Command writing audio to stdout
+	Reader io.ReadCloser // Already opened audio data
+}
+
+// Resolver turns URLs into tracks and their audio
+type Resolver interface {
+	Name() string
+	// Claims returns whether the resolver may handle rawURL, without using the network
+	Claims(rawURL string) bool
+	// Resolve looks up the track behind rawURL
+	Resolve(ctx context.Context, rawURL string) (Track, error)
+	// Open returns the audio of rawURL, ctx only limits lookups and not the playback of the source.
+	// Commands are returned unstarted.
+	Open(ctx context.Context, rawURL string) (Source, error)
+}
+
+// Resolvers are tried in order, the first one claiming a URL (and not returning ErrNotClaimed) handles it
+var Resolvers = []Resolver{File{}, HTTPStream{}, YtDL{}}
+
+// Resolve looks up rawURL with the first resolver handling it
+func Resolve(ctx context.Context, rawURL string) (Track, error) {
+	for _, r := range Resolvers {
+		if !r.Claims(rawURL) {
+			continue
+		}
+		track, err := r.Resolve(ctx, rawURL)
+		if errors.Is(err, ErrNotClaimed) {
+			continue
+		}
+		return complete(track, r, rawURL), err
+	}
+	return Track{}, ErrNoResolver
+}
+
+// Open returns the audio of rawURL from the first resolver handling it
+func Open(ctx context.Context, rawURL string) (Source, error) {
+	for _, r := range Resolvers {
+		if !r.Claims(rawURL) {
+			continue
+		}
+		source, err := r.Open(ctx, rawURL)
+		if errors.Is(err, ErrNotClaimed) {
+			continue
+		}
+		source.Track = complete(source.Track, r, rawURL)
+		return source, err
+	}
+	return Source{}, ErrNoResolver
+}
+
+func complete(track Track, r Resolver, rawURL string) Track {
+	track.Resolver = r.Name()
+	if track.URL == "" {
+		track.URL = rawURL
+	}
+	if track.Title == "" {
+		track.Title = rawURL
+	}
+	return track
+}
@@ -0,0 +1,99 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+// declining claims every URL but always hands it on
+type declining struct{}
+
+func (declining) Name() string                                   { return "declining" }
+func (declining) Claims(string) bool                             { return true }
+func (declining) Resolve(context.Context, string) (Track, error) { return Track{}, ErrNotClaimed }
+func (declining) Open(context.Context, string) (Source, error)   { return Source{}, ErrNotClaimed }
+
+func TestResolve(t *testing.T) {
+	defer func(resolvers []Resolver) { Resolvers = resolvers }(Resolvers)
+	Resolvers = []Resolver{declining{}, Fake{
+		Tracks: map[string]Track{
+			"https://example.com/song":     {Title: "Song", Duration: 3 * time.Minute},
+			"https://example.com/untitled": {},
+		},
+		Audio: []byte("audio"),
+	}}
+
+	tests := []struct {
+		url      string
+		title    string
+		resolver string
+		err      error
+	}{
+		{"https://example.com/song", "Song", "fake", nil},
+		{"https://example.com/untitled", "https://example.com/untitled", "fake", nil},
+		{"https://example.com/missing", "", "", ErrNoResolver},
+	}
+
+	for _, tt := range tests {
+		track, err := Resolve(context.Background(), tt.url)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("Resolve(%q) error = %v, want %v", tt.url, err, tt.err)
+			continue
+		}
+		if track.Title != tt.title || track.Resolver != tt.resolver {
+			t.Errorf("Resolve(%q) = %q from %q, want %q from %q", tt.url, track.Title, track.Resolver, tt.title, tt.resolver)
+		}
+	}
+
+	source, err := Open(context.Background(), "https://example.com/song")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer source.Reader.Close()
+	if data, _ := io.ReadAll(source.Reader); string(data) != "audio" || source.Duration != 3*time.Minute {
+		t.Errorf("Open returned %q lasting %v, want %q lasting %v", data, source.Duration, "audio", 3*time.Minute)
+	}
+}
+
+func TestFileClaims(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected bool
+	}{
+		{"https://example.com/music/song.mp3", true},
+		{"https://example.com/music/song.FLAC?token=1", true},
+		{"https://example.com/watch?v=song.mp3", false},
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ", false},
+		{"file:///music/song.mp3", false},
+	}
+
+	for _, tt := range tests {
+		if got := (File{}).Claims(tt.url); got != tt.expected {
+			t.Errorf("File.Claims(%q) = %v, want %v", tt.url, got, tt.expected)
+		}
+	}
+}
+
+func TestHTTPStreamClaims(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected bool
+	}{
+		{"https://radio.example.com:8000/live", true},
+		{"http://example.com/station.m3u", true},
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ", false},
+		{"https://youtu.be/dQw4w9WgXcQ", false},
+		{"https://soundcloud.com/artist/track", false},
+		{"https://artist.bandcamp.com/track/song", false},
+		{"file:///music/song.mp3", false},
+	}
+
+	for _, tt := range tests {
+		if got := (HTTPStream{}).Claims(tt.url); got != tt.expected {
+			t.Errorf("HTTPStream.Claims(%q) = %v, want %v", tt.url, got, tt.expected)
+		}
+	}
+}
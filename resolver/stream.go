@@ -0,0 +1,45 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/iotku/mumzic/httpstream"
+	"github.com/iotku/mumzic/youtubedl"
+)
+
+// HTTPStream handles Icecast/Shoutcast stations, plain HTTP audio and M3U/PLS station files (see httpstream)
+type HTTPStream struct{}
+
+func (HTTPStream) Name() string { return "stream" }
+
+// Claims takes any http(s) URL except those of sites which are left to YtDL, saving a request to probe them
+func (HTTPStream) Claims(rawURL string) bool {
+	return (strings.HasPrefix(rawURL, "http://") || strings.HasPrefix(rawURL, "https://")) && !youtubedl.IsSiteURL(rawURL)
+}
+
+// Resolve probes the URL (usually with a HEAD request) without connecting to the stream
+func (HTTPStream) Resolve(_ context.Context, rawURL string) (Track, error) {
+	stream, err := httpstream.Probe(rawURL)
+	if errors.Is(err, httpstream.ErrNotStream) {
+		return Track{}, ErrNotClaimed
+	} else if err != nil {
+		return Track{}, err
+	}
+	return Track{URL: rawURL, Title: stream.Name, Live: stream.Live, Stream: true}, nil
+}
+
+// Open connects to the stream, the Reader of the source is the *httpstream.Stream
+func (HTTPStream) Open(_ context.Context, rawURL string) (Source, error) {
+	stream, err := httpstream.Open(rawURL)
+	if errors.Is(err, httpstream.ErrNotStream) {
+		return Source{}, ErrNotClaimed
+	} else if err != nil {
+		return Source{}, err
+	}
+	return Source{
+		Track:  Track{URL: rawURL, Title: stream.Name, Live: stream.Live, Stream: true},
+		Reader: stream,
+	}, nil
+}
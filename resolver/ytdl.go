@@ -0,0 +1,60 @@
+package resolver
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/iotku/mumzic/cache"
+	"github.com/iotku/mumzic/youtubedl"
+)
+
+// YtDL handles anything yt-dlp supports, it claims every http(s) URL so it should come last
+type YtDL struct{}
+
+func (YtDL) Name() string { return "yt-dlp" }
+
+func (YtDL) Claims(rawURL string) bool {
+	return strings.HasPrefix(rawURL, "http://") || strings.HasPrefix(rawURL, "https://")
+}
+
+func (YtDL) Resolve(ctx context.Context, rawURL string) (Track, error) {
+	metadata, err := youtubedl.GetMetadata(ctx, rawURL)
+	if err != nil {
+		return Track{}, err
+	}
+	track := Track{
+		URL:      rawURL,
+		Title:    metadata.Title,
+		Uploader: metadata.Uploader,
+		Duration: metadata.Length(),
+		Live:     metadata.Live(),
+	}
+	if metadata.Extractor == "Youtube" {
+		track.VideoID = metadata.ID
+	}
+	for _, v := range metadata.Chapters {
+		track.Chapters = append(track.Chapters, Chapter{
+			Title: v.Title,
+			Start: time.Duration(v.StartTime * float64(time.Second)),
+			End:   time.Duration(v.EndTime * float64(time.Second)),
+		})
+	}
+	return track, nil
+}
+
+// Open plays rawURL from the download cache if possible, otherwise it is streamed through yt-dlp
+// (and downloaded into the cache in the background)
+func (y YtDL) Open(ctx context.Context, rawURL string) (Source, error) {
+	track, err := y.Resolve(ctx, rawURL)
+	if err != nil {
+		track = Track{URL: rawURL} // yt-dlp may still manage to stream it
+	}
+	if path, ok := cache.Lookup(rawURL); ok {
+		return Source{Track: track, Path: path}, nil
+	}
+	if err == nil && !track.Live {
+		cache.Store(rawURL)
+	}
+	return Source{Track: track, Cmd: youtubedl.GetYtDLCommand(context.Background(), rawURL)}, nil
+}
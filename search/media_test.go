@@ -0,0 +1,26 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/iotku/mumzic/database"
+	"github.com/iotku/mumzic/database/dbtest"
+)
+
+func TestMediaColumns(t *testing.T) {
+	oldDB := database.MediaDB
+	database.MediaDB = nil
+	if columns := mediaColumns(); len(columns) != 0 {
+		t.Errorf("mediaColumns without a media database = %v, want none", columns)
+	}
+	database.MediaDB = oldDB
+
+	dbtest.UseMediaDB(t, nil, false)
+	if columns := mediaColumns(); !columns["artist"] || columns["genre"] {
+		t.Errorf("mediaColumns = %v, want artist without genre", columns)
+	}
+	dbtest.UseMediaDB(t, nil, true)
+	if columns := mediaColumns(); !columns["artist"] || !columns["genre"] || !columns["year"] {
+		t.Errorf("mediaColumns after opening another media database = %v, want genre and year", columns)
+	}
+}
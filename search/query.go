@@ -0,0 +1,227 @@
+package search
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/iotku/mumzic/database"
+)
+
+// Fields can be searched with field:value, genre and year only if media.db has those columns
+var Fields = []string{"artist", "album", "title", "genre", "year"}
+
+// Query is a search of the local media database: Text is matched against artist and title,
+// Fields (field name to value) against their columns. All of them have to match.
+type Query struct {
+	Text   string
+	Fields map[string]string
+}
+
+// ParseQuery parses searches like `genre:jazz artist:"miles davis" blue`, values with spaces have to be quoted
+func ParseQuery(s string) (Query, error) {
+	tokens, err := splitQuery(s)
+	if err != nil {
+		return Query{}, err
+	}
+
+	query := Query{Fields: make(map[string]string)}
+	var text []string
+	for _, token := range tokens {
+		field, value, ok := strings.Cut(token, ":")
+		field = strings.ToLower(field)
+		if !ok || !isField(field) { // e.g. "AC:DC"
+			text = append(text, token)
+			continue
+		}
+		if value == "" {
+			return Query{}, errors.New("no value given for " + field + ":")
+		}
+		query.Fields[field] = value
+	}
+	query.Text = strings.Join(text, " ")
+	return query, nil
+}
+
+// splitQuery splits s at spaces outside of double quotes, removing the quotes
+func splitQuery(s string) ([]string, error) {
+	var tokens []string
+	var token strings.Builder
+	var quoted, inToken bool
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted, inToken = !quoted, true
+		case r == ' ' && !quoted:
+			if inToken {
+				tokens = append(tokens, token.String())
+				token.Reset()
+				inToken = false
+			}
+		default:
+			token.WriteRune(r)
+			inToken = true
+		}
+	}
+	if quoted {
+		return nil, errors.New("missing closing quote")
+	}
+	if inToken {
+		tokens = append(tokens, token.String())
+	}
+	return tokens, nil
+}
+
+func isField(name string) bool {
+	for _, v := range Fields {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+// IsEmpty reports whether the query matches every track
+func (query Query) IsEmpty() bool {
+	return query.Text == "" && len(query.Fields) == 0
+}
+
+// String returns the query in the form ParseQuery reads
+func (query Query) String() string {
+	var parts []string
+	if query.Text != "" {
+		parts = append(parts, query.Text)
+	}
+	fields := make([]string, 0, len(query.Fields))
+	for field := range query.Fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		value := query.Fields[field]
+		if strings.Contains(value, " ") {
+			value = `"` + value + `"`
+		}
+		parts = append(parts, field+":"+value)
+	}
+	return strings.Join(parts, " ")
+}
+
+// where returns the SQL condition for the query, columns are those of the music table
+func (query Query) where(columns map[string]bool) (string, []interface{}, error) {
+	conditions := []string{"1"}
+	var args []interface{}
+	if query.Text != "" {
+		conditions = append(conditions, `(artist || ' ' || title) LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(query.Text)+"%")
+	}
+	for _, field := range Fields { // Fixed order, so the statement only depends on which fields are given
+		value, ok := query.Fields[field]
+		if !ok {
+			continue
+		}
+		if !columns[field] {
+			return "", nil, errors.New("media.db has no " + field + " information")
+		}
+		if field == "year" { // year:199 matches the 1990s
+			conditions = append(conditions, `CAST(year AS TEXT) LIKE ? ESCAPE '\'`)
+			args = append(args, escapeLike(value)+"%")
+			continue
+		}
+		conditions = append(conditions, field+` LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(value)+"%")
+	}
+	return strings.Join(conditions, " AND "), args, nil
+}
+
+var (
+	columnsMu    sync.Mutex
+	columnsDB    *sql.DB // The media database musicColumns were read from
+	musicColumns map[string]bool
+)
+
+// mediaColumns returns the (lower case) columns of the music table, which vary with the version of the generator.
+// They are only cached once read, so a media database which isn't open yet is looked at again next time.
+func mediaColumns() map[string]bool {
+	columnsMu.Lock()
+	defer columnsMu.Unlock()
+	if len(musicColumns) != 0 && columnsDB == database.MediaDB {
+		return musicColumns
+	}
+
+	columns := make(map[string]bool)
+	rows := makeDbQuery("SELECT name FROM pragma_table_info('music')")
+	if rows == nil {
+		return columns
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		checkErrPanic(rows.Scan(&name))
+		columns[strings.ToLower(name)] = true
+	}
+	checkErrPanic(rows.Err())
+	musicColumns, columnsDB = columns, database.MediaDB
+	return columns
+}
+
+// Track is a track of the local media database, Genre and Year are empty if media.db doesn't have them
+type Track struct {
+	ID                         int
+	Artist, Album, Title, Path string
+	Genre, Year                string
+}
+
+// trackColumns returns the columns to select for scanTrack
+func trackColumns() string {
+	columns := "ROWID, artist, album, title, path"
+	for _, optional := range []string{"genre", "year"} {
+		if mediaColumns()[optional] {
+			columns += ", COALESCE(CAST(" + optional + " AS TEXT), '')"
+		} else {
+			columns += ", ''"
+		}
+	}
+	return columns
+}
+
+func scanTrack(rows *sql.Rows) Track {
+	var track Track
+	checkErrPanic(rows.Scan(&track.ID, &track.Artist, &track.Album, &track.Title, &track.Path, &track.Genre, &track.Year))
+	return track
+}
+
+// ErrNoMedia is returned when there is no local media database to search
+var ErrNoMedia = errors.New("no local media database")
+
+// RandomTracks returns up to amount random tracks matching query, leaving out those at the paths in exclude
+func RandomTracks(query Query, exclude []string, amount int) ([]Track, error) {
+	if database.GetMaxID() == 0 {
+		return nil, ErrNoMedia
+	}
+	where, args, err := query.where(mediaColumns())
+	if err != nil {
+		return nil, err
+	}
+	if len(exclude) != 0 {
+		where += " AND path NOT IN (?" + strings.Repeat(", ?", len(exclude)-1) + ")"
+		for _, v := range exclude {
+			args = append(args, v)
+		}
+	}
+	args = append(args, amount)
+
+	rows := makeDbQuery("SELECT "+trackColumns()+" FROM music WHERE "+where+" ORDER BY random() LIMIT ?", args...)
+	if rows == nil {
+		return nil, ErrNoMedia
+	}
+	defer rows.Close()
+	var tracks []Track
+	for rows.Next() {
+		tracks = append(tracks, scanTrack(rows))
+	}
+	checkErrPanic(rows.Err())
+	return tracks, nil
+}
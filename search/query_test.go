@@ -0,0 +1,58 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected Query
+	}{
+		{"", Query{Fields: map[string]string{}}},
+		{"blue in green", Query{Text: "blue in green", Fields: map[string]string{}}},
+		{"genre:jazz", Query{Fields: map[string]string{"genre": "jazz"}}},
+		{`Artist:"miles davis" blue`, Query{Text: "blue", Fields: map[string]string{"artist": "miles davis"}}},
+		{`"kind of" year:1959`, Query{Text: "kind of", Fields: map[string]string{"year": "1959"}}},
+		{"AC:DC", Query{Text: "AC:DC", Fields: map[string]string{}}},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Errorf("ParseQuery(%q) failed: %v", tt.query, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("ParseQuery(%q) = %+v, want %+v", tt.query, got, tt.expected)
+		}
+		if again, _ := ParseQuery(got.String()); !reflect.DeepEqual(again, got) {
+			t.Errorf("ParseQuery(%q) = %+v, doesn't round trip %+v", got.String(), again, got)
+		}
+	}
+
+	for _, query := range []string{"genre:", `artist:"miles`} {
+		if _, err := ParseQuery(query); err == nil {
+			t.Errorf("ParseQuery(%q) succeeded, want error", query)
+		}
+	}
+}
+
+func TestQueryWhere(t *testing.T) {
+	columns := map[string]bool{"artist": true, "album": true, "title": true, "path": true}
+	query := Query{Text: "50%", Fields: map[string]string{"title": "so_what", "artist": "miles"}}
+	where, args, err := query.where(columns)
+	if err != nil {
+		t.Fatalf("where failed: %v", err)
+	}
+	expectedWhere := `1 AND (artist || ' ' || title) LIKE ? ESCAPE '\' AND artist LIKE ? ESCAPE '\' AND title LIKE ? ESCAPE '\'`
+	expectedArgs := []interface{}{`%50\%%`, "%miles%", `%so\_what%`}
+	if where != expectedWhere || !reflect.DeepEqual(args, expectedArgs) {
+		t.Errorf("where = %q %v, want %q %v", where, args, expectedWhere, expectedArgs)
+	}
+
+	if _, _, err := (Query{Fields: map[string]string{"genre": "jazz"}}).where(columns); err == nil {
+		t.Error("where with genre succeeded without a genre column, want error")
+	}
+}
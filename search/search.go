@@ -2,8 +2,11 @@ package search
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"path/filepath"
+	"strings"
 
 	"github.com/iotku/mumzic/database"
 	_ "github.com/mattn/go-sqlite3"
@@ -53,12 +56,56 @@ func GetTrackById(trackID int) (human, path string) {
 	return
 }
 
-func FindArtistTitle(Query string) []string {
-	Query = fmt.Sprintf("%%%s%%", Query)
-	rows := makeDbQuery("SELECT ROWID, * FROM music where (artist || \" \" || title)  LIKE ? LIMIT 25", Query)
+// GetTrackIDByPath returns the ID of the track stored at path, falling back to the only track with the same
+// file name (for playlists made with the library at another location). 0 is returned if there is no such track.
+func GetTrackIDByPath(path string) int {
+	if database.GetMaxID() == 0 {
+		return 0
+	}
+
+	var id int
+	err := database.MediaDB.QueryRow("SELECT ROWID FROM music WHERE path = ?", path).Scan(&id)
+	if err == nil {
+		return id
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		checkErrPanic(err)
+	}
+
+	rows := makeDbQuery("SELECT ROWID FROM music WHERE path LIKE ? ESCAPE '\\' LIMIT 2", "%/"+escapeLike(filepath.Base(path)))
+	if rows == nil {
+		return 0
+	}
+	defer rows.Close()
+	var ids []int
+	for rows.Next() {
+		checkErrPanic(rows.Scan(&id))
+		ids = append(ids, id)
+	}
+	checkErrPanic(rows.Err())
+	if len(ids) != 1 {
+		return 0
+	}
+	return ids[0]
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// Find returns up to 25 tracks matching a query (see ParseQuery) formatted for chat
+func Find(q string) ([]string, error) {
+	query, err := ParseQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	where, args, err := query.where(mediaColumns())
+	if err != nil {
+		return nil, err
+	}
+	rows := makeDbQuery("SELECT ROWID, artist, album, title, path FROM music WHERE "+where+" LIMIT 25", args...)
 
 	if rows == nil { // DB was null
-		return []string{}
+		return []string{}, nil
 	}
 
 	var rowID int
@@ -71,7 +118,7 @@ func FindArtistTitle(Query string) []string {
 	}
 	checkErrPanic(rows.Close())
 
-	return output
+	return output, nil
 }
 
 // Helper Functions
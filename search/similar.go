@@ -0,0 +1,101 @@
+package search
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/iotku/mumzic/database"
+)
+
+// How much each thing a track has in common with another adds to its similarity score
+const (
+	sameArtist = 4
+	sameAlbum  = 3
+	sameGenre  = 2
+	nearYear   = 1 // Released within yearRange years
+	yearRange  = 5
+)
+
+// Similar is a track with its similarity score, the higher the more alike
+type Similar struct {
+	Track
+	Score int
+}
+
+// TrackByPath returns the library track stored at path, see GetTrackIDByPath
+func TrackByPath(path string) (Track, bool) {
+	id := GetTrackIDByPath(path)
+	if id == 0 {
+		return Track{}, false
+	}
+	rows := makeDbQuery("SELECT "+trackColumns()+" FROM music WHERE ROWID = ?", id)
+	if rows == nil {
+		return Track{}, false
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		checkErrPanic(rows.Err())
+		return Track{}, false
+	}
+	return scanTrack(rows), true
+}
+
+// SimilarTracks returns up to amount tracks sharing the artist, album, genre or release years of seed, most similar
+// first (in random order among equals). Tracks at the paths in exclude and seed itself are left out.
+func SimilarTracks(seed Track, exclude []string, amount int) ([]Similar, error) {
+	if database.GetMaxID() == 0 {
+		return nil, ErrNoMedia
+	}
+	var terms []string
+	var args []interface{}
+	add := func(term string, value interface{}) {
+		terms = append(terms, term)
+		args = append(args, value)
+	}
+	if seed.Artist != "" {
+		add("(artist = ? COLLATE NOCASE) * "+strconv.Itoa(sameArtist), seed.Artist)
+	}
+	if seed.Album != "" && seed.Artist != "" { // Albums are only the same by the same artist (not "Greatest Hits")
+		terms = append(terms, "(album = ? COLLATE NOCASE AND artist = ? COLLATE NOCASE) * "+strconv.Itoa(sameAlbum))
+		args = append(args, seed.Album, seed.Artist)
+	}
+	columns := mediaColumns()
+	if seed.Genre != "" && columns["genre"] {
+		add("(genre = ? COLLATE NOCASE) * "+strconv.Itoa(sameGenre), seed.Genre)
+	}
+	if year, err := strconv.Atoi(yearOf(seed.Year)); err == nil && columns["year"] {
+		add("(ABS(CAST(SUBSTR(year, 1, 4) AS INTEGER) - ?) <= "+strconv.Itoa(yearRange)+") * "+strconv.Itoa(nearYear), year)
+	}
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	exclude = append(exclude[:len(exclude):len(exclude)], seed.Path)
+	for _, v := range exclude {
+		args = append(args, v)
+	}
+	args = append(args, amount)
+	rows := makeDbQuery("SELECT * FROM (SELECT "+trackColumns()+", "+strings.Join(terms, " + ")+" AS score FROM music"+
+		" WHERE path NOT IN (?"+strings.Repeat(", ?", len(exclude)-1)+")) WHERE score > 0 ORDER BY score DESC, random() LIMIT ?",
+		args...)
+	if rows == nil {
+		return nil, ErrNoMedia
+	}
+	defer rows.Close()
+	var similar []Similar
+	for rows.Next() {
+		var v Similar
+		checkErrPanic(rows.Scan(&v.ID, &v.Artist, &v.Album, &v.Title, &v.Path, &v.Genre, &v.Year, &v.Score))
+		similar = append(similar, v)
+	}
+	checkErrPanic(rows.Err())
+	return similar, nil
+}
+
+// yearOf returns the year of dates like "1959-08-17"
+func yearOf(date string) string {
+	if len(date) > 4 {
+		return date[:4]
+	}
+	return date
+}
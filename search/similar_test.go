@@ -0,0 +1,56 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/iotku/mumzic/database/dbtest"
+)
+
+func TestSimilarTracks(t *testing.T) {
+	tracks := []dbtest.Track{
+		{Artist: "Miles Davis", Album: "Kind of Blue", Title: "So What", Path: "/music/so-what.flac", Genre: "Jazz", Year: "1959"},
+		{Artist: "Miles Davis", Album: "Kind of Blue", Title: "Blue in Green", Path: "/music/blue-in-green.flac", Genre: "Jazz", Year: "1959-08-17"},
+		{Artist: "miles davis", Album: "Bitches Brew", Title: "Spanish Key", Path: "/music/spanish-key.flac", Genre: "Fusion", Year: "1970"},
+		{Artist: "John Coltrane", Album: "Kind of Blue", Title: "Giant Steps", Path: "/music/giant-steps.flac", Genre: "Jazz", Year: "1960"},
+		{Artist: "Daft Punk", Album: "Discovery", Title: "One More Time", Path: "/music/one-more-time.flac", Genre: "House", Year: "2001"},
+	}
+	seed := Track{ID: 1, Artist: "Miles Davis", Album: "Kind of Blue", Title: "So What", Path: "/music/so-what.flac", Genre: "Jazz", Year: "1959"}
+
+	tests := []struct {
+		name     string
+		extended bool // Whether media.db has genre and year
+		exclude  []string
+		amount   int
+		expected []string // Paths, most similar first
+	}{
+		{"ranked", true, nil, 10, []string{"/music/blue-in-green.flac", "/music/spanish-key.flac", "/music/giant-steps.flac"}},
+		{"recently played", true, []string{"/music/blue-in-green.flac"}, 10, []string{"/music/spanish-key.flac", "/music/giant-steps.flac"}},
+		{"amount", true, nil, 1, []string{"/music/blue-in-green.flac"}},
+		{"no genre or year", false, nil, 10, []string{"/music/blue-in-green.flac", "/music/spanish-key.flac"}},
+	}
+
+	for _, tt := range tests {
+		dbtest.UseMediaDB(t, tracks, tt.extended)
+		similar, err := SimilarTracks(seed, tt.exclude, tt.amount)
+		if err != nil {
+			t.Errorf("%s: SimilarTracks failed: %v", tt.name, err)
+			continue
+		}
+		var paths []string
+		for _, v := range similar {
+			paths = append(paths, v.Path)
+		}
+		if !reflect.DeepEqual(paths, tt.expected) {
+			t.Errorf("%s: SimilarTracks = %v, want %v", tt.name, paths, tt.expected)
+		}
+	}
+
+	dbtest.UseMediaDB(t, tracks, true)
+	if similar, err := SimilarTracks(Track{Path: "/music/unknown.flac"}, nil, 10); err != nil || similar != nil {
+		t.Errorf("SimilarTracks of a track without tags = %v, %v, want nothing", similar, err)
+	}
+	if similar, _ := SimilarTracks(seed, nil, 10); len(similar) == 0 || similar[0].Score != sameArtist+sameAlbum+sameGenre+nearYear {
+		t.Errorf("SimilarTracks scored %v, want %d for the same artist, album, genre and year", similar, sameArtist+sameAlbum+sameGenre+nearYear)
+	}
+}
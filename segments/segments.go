@@ -0,0 +1,159 @@
+package segments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultAPI is the public SponsorBlock server, used when the source is "sponsorblock"
+const DefaultAPI = "https://sponsor.ajay.app"
+
+// Categories of segments which are skipped
+var Categories = []string{"sponsor", "music_offtopic"}
+
+// Segment is a part of a video to skip
+type Segment struct {
+	Start, End time.Duration
+	Category   string
+}
+
+// Provider looks up the segments of YouTube videos
+type Provider interface {
+	Segments(ctx context.Context, videoID string) ([]Segment, error)
+}
+
+// NewProvider returns the provider for source (the Segments config value):
+// "sponsorblock" for the public server, the URL of another SponsorBlock compatible server
+// or the path of a local JSON file. An empty source returns nil, disabling skipping.
+func NewProvider(source string) Provider {
+	switch {
+	case source == "":
+		return nil
+	case source == "sponsorblock":
+		return API{BaseURL: DefaultAPI}
+	case strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://"):
+		return API{BaseURL: source}
+	default:
+		return File{Path: source}
+	}
+}
+
+// apiSegment is a segment as returned by the SponsorBlock API (and stored in local files)
+type apiSegment struct {
+	Segment  [2]float64 `json:"segment"` // Start and end in seconds
+	Category string     `json:"category"`
+}
+
+func convert(in []apiSegment) []Segment {
+	var out []Segment
+	for _, v := range in {
+		if !wanted(v.Category) || v.Segment[1] <= v.Segment[0] {
+			continue
+		}
+		out = append(out, Segment{
+			Start:    time.Duration(v.Segment[0] * float64(time.Second)),
+			End:      time.Duration(v.Segment[1] * float64(time.Second)),
+			Category: v.Category,
+		})
+	}
+	return out
+}
+
+func wanted(category string) bool {
+	for _, v := range Categories {
+		if v == category {
+			return true
+		}
+	}
+	return false
+}
+
+// How long looked up segments are reused, a video is looked up again on every seek otherwise
+const cacheTTL = time.Hour
+
+type cachedSegments struct {
+	segments []Segment
+	fetched  time.Time
+}
+
+var cache = struct {
+	sync.Mutex
+	entries map[string]cachedSegments
+}{entries: make(map[string]cachedSegments)}
+
+var client = &http.Client{Timeout: 10 * time.Second}
+
+// API looks segments up on a SponsorBlock compatible server
+type API struct {
+	BaseURL string
+}
+
+func (api API) Segments(ctx context.Context, videoID string) ([]Segment, error) {
+	key := api.BaseURL + " " + videoID
+	cache.Lock()
+	entry, ok := cache.entries[key]
+	cache.Unlock()
+	if ok && time.Since(entry.fetched) < cacheTTL {
+		return entry.segments, nil
+	}
+
+	categories, err := json.Marshal(Categories)
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{"videoID": {videoID}, "categories": {string(categories)}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(api.BaseURL, "/")+"/api/skipSegments?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var segments []Segment
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var found []apiSegment
+		if err := json.NewDecoder(resp.Body).Decode(&found); err != nil {
+			return nil, err
+		}
+		segments = convert(found)
+	case http.StatusNotFound: // The video has no segments
+	default:
+		return nil, errors.New("segment lookup responded with status " + strconv.Itoa(resp.StatusCode))
+	}
+
+	cache.Lock()
+	cache.entries[key] = cachedSegments{segments, time.Now()}
+	cache.Unlock()
+	return segments, nil
+}
+
+// File reads segments from a local JSON file mapping video IDs to segments in the format of the SponsorBlock API:
+//
+//	{"dQw4w9WgXcQ": [{"segment": [0, 12.5], "category": "music_offtopic"}]}
+type File struct {
+	Path string
+}
+
+func (file File) Segments(_ context.Context, videoID string) ([]Segment, error) {
+	data, err := os.ReadFile(file.Path) // #nosec G304 -- path is set by the bot owner in config.db
+	if err != nil {
+		return nil, err
+	}
+	var videos map[string][]apiSegment
+	if err := json.Unmarshal(data, &videos); err != nil {
+		return nil, err
+	}
+	return convert(videos[videoID]), nil
+}
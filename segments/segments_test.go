@@ -0,0 +1,73 @@
+package segments
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const videoSegments = `[
+	{"segment": [0, 12.5], "category": "music_offtopic", "UUID": "a"},
+	{"segment": [60, 90], "category": "sponsor", "UUID": "b"},
+	{"segment": [100, 110], "category": "selfpromo", "UUID": "c"}
+]`
+
+var expected = []Segment{
+	{0, 12500 * time.Millisecond, "music_offtopic"},
+	{60 * time.Second, 90 * time.Second, "sponsor"},
+}
+
+func TestAPI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/skipSegments" || r.URL.Query().Get("videoID") != "withSegments" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(videoSegments))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		videoID  string
+		expected []Segment
+	}{
+		{"withSegments", expected},
+		{"withoutSegments", nil},
+	}
+
+	provider := NewProvider(server.URL)
+	for _, tt := range tests {
+		got, err := provider.Segments(context.Background(), tt.videoID)
+		if err != nil {
+			t.Errorf("Segments(%q) failed: %v", tt.videoID, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("Segments(%q) = %v, want %v", tt.videoID, got, tt.expected)
+		}
+	}
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "segments.json")
+	if err := os.WriteFile(path, []byte(`{"withSegments": `+videoSegments+`}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	provider := NewProvider(path)
+	got, err := provider.Segments(context.Background(), "withSegments")
+	if err != nil {
+		t.Fatalf("Segments failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Segments = %v, want %v", got, expected)
+	}
+	if got, _ := provider.Segments(context.Background(), "missing"); len(got) != 0 {
+		t.Errorf("Segments of a missing video = %v, want none", got)
+	}
+}
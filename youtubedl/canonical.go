@@ -0,0 +1,80 @@
+package youtubedl
+
+import (
+	"net/url"
+	"strings"
+)
+
+// youtubeHosts are the hosts serving YouTube videos, see CanonicalURL
+var youtubeHosts = map[string]bool{"youtube.com": true, "m.youtube.com": true, "music.youtube.com": true, "youtu.be": true,
+	"youtube-nocookie.com": true}
+
+// siteHosts are hosts of sites yt-dlp extracts audio from (besides youtubeHosts), subdomains included
+var siteHosts = []string{"soundcloud.com", "bandcamp.com", "vimeo.com", "twitch.tv", "dailymotion.com", "mixcloud.com"}
+
+// IsSiteURL returns true for URLs of sites yt-dlp is known to handle, such as YouTube and SoundCloud.
+// They are pages rather than audio, so there is no point probing them for streams.
+func IsSiteURL(rawURL string) bool {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return false
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if youtubeHosts[host] {
+		return true
+	}
+	for _, v := range siteHosts {
+		if host == v || strings.HasSuffix(host, "."+v) {
+			return true
+		}
+	}
+	return false
+}
+
+// CanonicalURL returns a form of rawURL to tell whether two URLs are for the same track, e.g. youtu.be/x and
+// youtube.com/watch?v=x. It is meant for comparing only and may not be a working URL.
+func CanonicalURL(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+	path := strings.TrimSuffix(u.Path, "/")
+
+	if youtubeHosts[host] {
+		if id := youtubeID(host, path, u.Query()); id != "" {
+			return "https://youtube.com/watch?v=" + id
+		}
+	}
+
+	query := u.Query()
+	for key := range query {
+		if strings.HasPrefix(key, "utm_") || key == "si" || key == "feature" {
+			delete(query, key)
+		}
+	}
+	canonical := "https://" + host + path
+	if len(query) != 0 {
+		canonical += "?" + query.Encode() // Sorted by key
+	}
+	return canonical
+}
+
+// youtubeID returns the video ID of YouTube URLs to videos, "" for others (e.g. playlists)
+func youtubeID(host, path string, query url.Values) string {
+	if host == "youtu.be" {
+		return strings.TrimPrefix(path, "/")
+	}
+	if path == "/watch" {
+		return query.Get("v")
+	}
+	for _, prefix := range []string{"/shorts/", "/embed/", "/live/", "/v/"} {
+		if strings.HasPrefix(path, prefix) {
+			return strings.TrimPrefix(path, prefix)
+		}
+	}
+	return ""
+}
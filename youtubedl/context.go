@@ -0,0 +1,59 @@
+package youtubedl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Timeouts for yt-dlp invocations, these can be changed with command line flags (see main.go)
+var (
+	Timeout         = 30 * time.Second // Metadata, search and thumbnail lookups
+	DownloadTimeout = 10 * time.Minute // Downloading a whole track (see package cache)
+)
+
+// ErrTimeout is returned when yt-dlp (or a thumbnail download) takes longer than its timeout
+var ErrTimeout = errors.New("yt-dlp timed out")
+
+// ErrShutdown is returned for calls cancelled because the bot is shutting down
+var ErrShutdown = errors.New("shutting down")
+
+// shutdownCtx is cancelled by Shutdown, killing every yt-dlp process still running
+var shutdownCtx, shutdown = context.WithCancel(context.Background())
+
+// Shutdown kills all running yt-dlp processes and makes further calls fail immediately
+func Shutdown() {
+	shutdown()
+}
+
+// withTimeout derives a context from ctx which is also cancelled after timeout or on Shutdown
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	stop := context.AfterFunc(shutdownCtx, cancel)
+	context.AfterFunc(ctx, func() { stop() }) // stop watching for Shutdown once ctx is done either way
+	return ctx, cancel
+}
+
+// untilShutdown derives a context from ctx which is also cancelled on Shutdown, without a timeout.
+// ctx without cancellation (context.Background) is replaced by the Shutdown context itself.
+func untilShutdown(ctx context.Context) context.Context {
+	if ctx.Done() == nil {
+		return shutdownCtx
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(shutdownCtx, cancel)
+	context.AfterFunc(ctx, func() { stop() })
+	return ctx
+}
+
+// runError turns the error of a call made with ctx into a user facing error, what describes the call
+func runError(ctx context.Context, what string) error {
+	switch {
+	case shutdownCtx.Err() != nil:
+		return fmt.Errorf("%w: %s", ErrShutdown, what)
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("%w: %s", ErrTimeout, what)
+	}
+	return errors.New("YDL failed to " + what)
+}
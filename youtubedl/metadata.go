@@ -0,0 +1,155 @@
+package youtubedl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metadata is the subset of yt-dlp's -J output we use
+type Metadata struct {
+	ID         string      `json:"id"`
+	Title      string      `json:"title"`
+	Uploader   string      `json:"uploader"`
+	Duration   float64     `json:"duration"` // Seconds, 0 if unknown (e.g. live streams)
+	Thumbnail  string      `json:"thumbnail"`
+	Thumbnails []Thumbnail `json:"thumbnails"`
+	IsLive     bool        `json:"is_live"`
+	LiveStatus string      `json:"live_status"` // "is_live", "was_live", "not_live", ...
+	Chapters   []Chapter   `json:"chapters"`
+	WebpageURL string      `json:"webpage_url"`
+	Extractor  string      `json:"extractor_key"` // Site the URL belongs to, e.g. "Youtube"
+}
+
+type Thumbnail struct {
+	URL        string `json:"url"`
+	Width      int    `json:"width"`
+	Height     int    `json:"height"`
+	Preference int    `json:"preference"`
+}
+
+type Chapter struct {
+	Title     string  `json:"title"`
+	StartTime float64 `json:"start_time"`
+	EndTime   float64 `json:"end_time"`
+}
+
+// How long fetched metadata is reused and how many URLs are remembered
+const (
+	metadataTTL       = 6 * time.Hour
+	metadataCacheSize = 512
+)
+
+type cachedMetadata struct {
+	metadata *Metadata
+	fetched  time.Time
+}
+
+var metadataCache = struct {
+	sync.Mutex
+	entries map[string]cachedMetadata
+	pending map[string]*sync.WaitGroup // fetches in progress, so concurrent callers share one yt-dlp run
+}{entries: make(map[string]cachedMetadata), pending: make(map[string]*sync.WaitGroup)}
+
+// GetMetadata returns the metadata for url, running yt-dlp only if it isn't cached already
+func GetMetadata(ctx context.Context, url string) (*Metadata, error) {
+	metadataCache.Lock()
+	for { // Another fetch may start (or fail) while we wait for one, so look again after each
+		if entry, ok := metadataCache.entries[url]; ok && time.Since(entry.fetched) < metadataTTL {
+			metadataCache.Unlock()
+			return entry.metadata, nil
+		}
+		wg, ok := metadataCache.pending[url]
+		if !ok {
+			break
+		}
+		metadataCache.Unlock()
+		wg.Wait()
+		metadataCache.Lock()
+	}
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	metadataCache.pending[url] = wg
+	metadataCache.Unlock()
+
+	metadata, err := fetchMetadata(ctx, url)
+
+	metadataCache.Lock()
+	delete(metadataCache.pending, url)
+	if err == nil {
+		cacheMetadata(url, metadata)
+	}
+	metadataCache.Unlock()
+	wg.Done()
+	return metadata, err
+}
+
+// CachedMetadata returns the metadata for url if it has already been fetched, it never runs yt-dlp
+func CachedMetadata(url string) (*Metadata, bool) {
+	metadataCache.Lock()
+	defer metadataCache.Unlock()
+	entry, ok := metadataCache.entries[url]
+	if !ok || time.Since(entry.fetched) >= metadataTTL {
+		return nil, false
+	}
+	return entry.metadata, true
+}
+
+// cacheMetadata stores metadata for url, evicting the oldest entry when full. metadataCache must be locked.
+func cacheMetadata(url string, metadata *Metadata) {
+	if len(metadataCache.entries) >= metadataCacheSize {
+		var oldest string
+		for key, entry := range metadataCache.entries {
+			if oldest == "" || entry.fetched.Before(metadataCache.entries[oldest].fetched) {
+				oldest = key
+			}
+		}
+		delete(metadataCache.entries, oldest)
+	}
+	metadataCache.entries[url] = cachedMetadata{metadata, time.Now()}
+}
+
+func fetchMetadata(ctx context.Context, url string) (*Metadata, error) {
+	ctx, cancel := withTimeout(ctx, Timeout)
+	defer cancel()
+	// #nosec G204 -- URL is strictly validated and '--' prevents argument injection
+	ytDL := exec.CommandContext(ctx, "yt-dlp", "--no-playlist", "-J", "--", url)
+	var output bytes.Buffer
+	ytDL.Stdout = &output
+	if err := ytDL.Run(); err != nil {
+		return nil, runError(ctx, "get metadata for: "+url)
+	}
+
+	var metadata Metadata
+	if err := json.Unmarshal(output.Bytes(), &metadata); err != nil {
+		return nil, errors.New("YDL returned invalid metadata for: " + url)
+	}
+	metadata.Title = strings.TrimSpace(metadata.Title)
+	return &metadata, nil
+}
+
+// Length returns the duration of the video, 0 if unknown
+func (metadata *Metadata) Length() time.Duration {
+	return time.Duration(metadata.Duration * float64(time.Second))
+}
+
+// Live returns whether url is a live stream which is currently on air, these have no length and can't be seeked
+func (metadata *Metadata) Live() bool {
+	return metadata.IsLive || metadata.LiveStatus == "is_live"
+}
+
+// ThumbnailURL returns the preferred thumbnail, yt-dlp lists thumbnails from worst to best
+func (metadata *Metadata) ThumbnailURL() string {
+	if metadata.Thumbnail != "" {
+		return metadata.Thumbnail
+	}
+	if len(metadata.Thumbnails) != 0 {
+		return metadata.Thumbnails[len(metadata.Thumbnails)-1].URL
+	}
+	return ""
+}
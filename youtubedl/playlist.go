@@ -0,0 +1,84 @@
+package youtubedl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// PlaylistEntry is a single item of a flat playlist, Title may be empty for some sites (e.g. SoundCloud)
+type PlaylistEntry struct {
+	URL      string
+	Title    string
+	Duration float64
+}
+
+type flatPlaylist struct {
+	Title   string `json:"title"`
+	Entries []struct {
+		URL        string  `json:"url"`
+		WebpageURL string  `json:"webpage_url"`
+		Title      string  `json:"title"`
+		Duration   float64 `json:"duration"`
+	} `json:"entries"`
+}
+
+// IsPlaylistURL returns true for URLs which only point to a playlist, rather than a video that happens to be
+// part of one (such as youtube.com/watch?v=...&list=...)
+func IsPlaylistURL(rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+	switch {
+	case strings.HasSuffix(host, "youtube.com"):
+		return parsed.Path == "/playlist" && parsed.Query().Get("list") != ""
+	case strings.HasSuffix(host, "soundcloud.com"):
+		return strings.Contains(parsed.Path, "/sets/")
+	}
+	return false
+}
+
+// GetPlaylist lists up to limit entries of a playlist without resolving each entry, which keeps it fast
+// for large playlists. Entries which aren't whitelisted are left out.
+func GetPlaylist(ctx context.Context, playlistURL string, limit int) (title string, entries []PlaylistEntry, err error) {
+	ctx, cancel := withTimeout(ctx, Timeout)
+	defer cancel()
+	// #nosec G204 -- URL is strictly validated and '--' prevents argument injection
+	ytDL := exec.CommandContext(ctx, "yt-dlp", "--yes-playlist", "--flat-playlist", "-J", "--playlist-end", strconv.Itoa(limit),
+		"--", playlistURL)
+	var output bytes.Buffer
+	ytDL.Stdout = &output
+	if err = ytDL.Run(); err != nil {
+		return "", nil, runError(ctx, "list playlist: "+playlistURL)
+	}
+
+	var playlist flatPlaylist
+	if err = json.Unmarshal(output.Bytes(), &playlist); err != nil {
+		return "", nil, errors.New("YDL returned an invalid playlist for: " + playlistURL)
+	}
+
+	for _, v := range playlist.Entries {
+		entryURL := v.WebpageURL
+		if entryURL == "" {
+			entryURL = v.URL
+		}
+		if !strings.HasPrefix(entryURL, "http") || !IsWhiteListedURL(entryURL) {
+			continue
+		}
+		entries = append(entries, PlaylistEntry{URL: entryURL, Title: strings.TrimSpace(v.Title), Duration: v.Duration})
+		if len(entries) == limit {
+			break
+		}
+	}
+	if len(entries) == 0 {
+		return playlist.Title, nil, errors.New("playlist has no playable entries")
+	}
+	return playlist.Title, entries, nil
+}
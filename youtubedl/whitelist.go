@@ -0,0 +1,244 @@
+package youtubedl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// whitelistFile holds one rule per line, lines starting with # are comments:
+//
+//	example.com              example.com only
+//	*.example.com            example.com and all of its subdomains
+//	example.com/music/*      a path pattern, * matches anything (patterns match path prefixes, whole segments
+//	                         unless they end in / or *, so example.com/pub doesn't match /public)
+//	example.com:8000         a non default port, other ports are always rejected
+//	regex:^https://x\.com/   a regular expression matched against the whole URL
+//	!cdn.example.com         a deny rule, denies win over any allow rule
+//	https://example.com/     a URL prefix (the old format), limited to that scheme. A query in it
+//	                         (https://youtube.com/watch?v=) has to start the query of the URL.
+//
+// URLs with credentials (user:pass@), schemes other than http(s) or . and .. path segments are never allowed.
+var whitelistFile = "whitelist.txt"
+
+var whitelist struct {
+	sync.RWMutex
+	rules []rule
+}
+
+type rule struct {
+	deny       bool
+	scheme     string // Empty for both http and https
+	host       string
+	subdomains bool           // Also match any subdomain of host
+	port       string         // Empty for the default port of the scheme
+	path       *regexp.Regexp // Matched against the decoded path
+	query      string         // Prefix of the raw query, from URL prefix rules
+	regex      *regexp.Regexp // Set for regex: rules, which ignore the fields above
+}
+
+func init() {
+	err := LoadWhitelist()
+	if err != nil {
+		log.Printf("failed to load allowed URLs: %v", err)
+	}
+}
+
+// LoadWhitelist (re)loads the rules from whitelistFile.
+// Lines which fail to parse are skipped and returned together as the error, the other rules still apply.
+func LoadWhitelist() error {
+	f, err := os.Open(whitelistFile) // #nosec G304 - Internal Helper method
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return errors.Join(scanRules(bufio.NewScanner(f))...)
+}
+
+// scanRules replaces the current rules with those read from scanner, returning an error for each invalid line
+func scanRules(scanner *bufio.Scanner) []error {
+	var rules []rule
+	var errs []error
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") { // Ignore # comments
+			continue
+		}
+
+		r, err := parseRule(line)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s line %d (%s): %w", whitelistFile, lineNum, line, err))
+			continue
+		}
+		if r.query != "" {
+			log.Printf("%s line %d (%s): matching queries by prefix is deprecated, use a path rule instead", whitelistFile, lineNum, line)
+		}
+		rules = append(rules, r)
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, err)
+	}
+
+	whitelist.Lock()
+	whitelist.rules = rules
+	whitelist.Unlock()
+	log.Printf("Loaded %d whitelist rules", len(rules))
+	return errs
+}
+
+func parseRule(line string) (rule, error) {
+	var r rule
+	if strings.HasPrefix(line, "!") {
+		r.deny = true
+		line = strings.TrimSpace(line[1:])
+	}
+
+	if pattern, ok := strings.CutPrefix(line, "regex:"); ok {
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			return r, err
+		}
+		r.regex = regex
+		return r, nil
+	}
+
+	if strings.HasPrefix(line, "http://") || strings.HasPrefix(line, "https://") {
+		u, err := url.Parse(line)
+		if err != nil {
+			return r, err
+		}
+		if u.User != nil || u.Fragment != "" {
+			return r, errors.New("URL prefixes can't have credentials or a fragment")
+		}
+		r.scheme, r.query = u.Scheme, u.RawQuery
+		line = u.Hostname() + u.EscapedPath()
+		if port := u.Port(); port != "" && !(r.scheme == "http" && port == "80") && !(r.scheme == "https" && port == "443") {
+			line = u.Hostname() + ":" + port + u.EscapedPath()
+		}
+	}
+
+	hostPort, pathPattern, hasPath := strings.Cut(line, "/")
+	host, port, hasPort := strings.Cut(hostPort, ":")
+	if hasPort {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return r, errors.New("invalid port " + port)
+		}
+		r.port = port
+	}
+
+	host = strings.ToLower(host)
+	if h, ok := strings.CutPrefix(host, "*."); ok {
+		r.subdomains = true
+		host = h
+	}
+	if !validHost(host) {
+		return r, errors.New("invalid host " + host)
+	}
+	r.host = host
+
+	if hasPath {
+		pattern, err := url.PathUnescape("/" + pathPattern)
+		if err != nil {
+			return r, err
+		}
+		parts := strings.Split(pattern, "*")
+		for i := range parts {
+			parts[i] = regexp.QuoteMeta(parts[i])
+		}
+		expr := "^" + strings.Join(parts, ".*")
+		if !strings.HasSuffix(pattern, "/") && !strings.HasSuffix(pattern, "*") {
+			expr += "(/|$)" // Whole segments only
+		}
+		r.path = regexp.MustCompile(expr)
+	}
+	return r, nil
+}
+
+// hasDotSegment reports whether the (decoded) path has . or .. segments, which servers resolve to other paths
+func hasDotSegment(path string) bool {
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "." || segment == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+func validHost(host string) bool {
+	if host == "" || strings.HasPrefix(host, ".") || strings.HasSuffix(host, ".") {
+		return false
+	}
+	for _, c := range host {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' && c != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+func (r rule) matches(u *url.URL, rawURL string) bool {
+	if r.regex != nil {
+		return r.regex.MatchString(rawURL)
+	}
+	if r.scheme != "" && r.scheme != u.Scheme {
+		return false
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host != r.host && !(r.subdomains && strings.HasSuffix(host, "."+r.host)) {
+		return false
+	}
+
+	port := u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if port != r.port {
+		return false
+	}
+
+	if r.path != nil {
+		path := u.Path
+		if path == "" {
+			path = "/"
+		}
+		if !r.path.MatchString(path) {
+			return false
+		}
+	}
+	return strings.HasPrefix(u.RawQuery, r.query)
+}
+
+// IsWhiteListedURL returns true if rawURL is allowed by an allow rule and not denied by any deny rule
+func IsWhiteListedURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.User != nil || u.Host == "" || hasDotSegment(u.Path) {
+		return false
+	}
+
+	whitelist.RLock()
+	defer whitelist.RUnlock()
+	if len(whitelist.rules) == 0 {
+		log.Println("IsWhiteListedURL was called, but there are no whitelist rules")
+	}
+
+	allowed := false
+	for _, r := range whitelist.rules {
+		if !r.matches(u, rawURL) {
+			continue
+		}
+		if r.deny {
+			return false
+		}
+		allowed = true
+	}
+	return allowed
+}
@@ -1,95 +1,34 @@
 package youtubedl
 
 import (
-	"bufio"
 	"bytes"
+	"context"
 	_ "embed"
+	"encoding/json"
 	"errors"
 	"image"
 	_ "image/png"
-	"log"
 	"net/http"
-	"os"
 	"os/exec"
 	"strconv"
 	"strings"
-
-	"layeh.com/gumble/gumbleffmpeg"
+	"time"
 )
 
-// ! Don't forget to end url prefix with / !
-var AllowedURLPrefixes []string
-var whitelistFile = "whitelist.txt"
-
-func init() {
-	err := LoadAllowedURLPrefixesFromFile()
-	if err != nil {
-		log.Printf("failed to load allowed URLs: %v", err)
-	}
-}
-
-// LoadAllowedURLPrefixesFromFile loads prefixes from each line of a provided txt file
-func LoadAllowedURLPrefixesFromFile() error {
-	f, err := os.Open(whitelistFile) // #nosec G304 - Internal Helper method
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	return scanURLPrefixes(bufio.NewScanner(f))
-}
-
-func scanURLPrefixes(scanner *bufio.Scanner) error {
-	AllowedURLPrefixes = AllowedURLPrefixes[:0] // reset
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" || strings.HasPrefix(line, "#") { // Ignore # comments
-			continue
-		}
-
-		if !strings.HasPrefix(line, "http://") && !strings.HasPrefix(line, "https://") {
-			log.Println("Invalid entry (" + line + ") must start with http:// or https:// ") // #nosec G706
-			continue
-		}
-
-		if !strings.HasSuffix(line, "/") { // We're not enforcing this, but this is a good idea.
-			log.Println("[WARN] " + whitelistFile + ": " + line + " does not end with trailing / This may allow yt-dl URL bypasses.") // #nosec G706
-		}
-
-		AllowedURLPrefixes = append(AllowedURLPrefixes, line)
-
-	}
-
-	log.Printf("Allowed URL prefixes: %v", AllowedURLPrefixes)
-	return scanner.Err()
-}
-
-// IsWhiteListedURL returns true if URL begins with an acceptable URL for ytdl
-func IsWhiteListedURL(url string) bool {
-	if len(AllowedURLPrefixes) == 0 {
-		log.Println("IsWhiteListedURL was called, but there are no allowedURLPrefixes")
-	}
-
-	for _, prefix := range AllowedURLPrefixes {
-		if strings.HasPrefix(url, prefix) {
-			return true
-		}
-	}
-	return false
-}
-
 // SearchYouTube searches for a video on YouTube using yt-dlp and returns the first result URL
-func SearchYouTube(query string) (string, error) {
+func SearchYouTube(ctx context.Context, query string) (string, error) {
 	if !IsWhiteListedURL("https://www.youtube.com/") {
 		return "", errors.New("YouTube is not whitelisted")
 	}
+	ctx, cancel := withTimeout(ctx, Timeout)
+	defer cancel()
 	// #nosec G204 '--' hopefully prevents argument injection
-	ytDL := exec.Command("yt-dlp", "--no-playlist", "--get-id", "--default-search", "ytsearch1", "--", query)
+	ytDL := exec.CommandContext(ctx, "yt-dlp", "--no-playlist", "--get-id", "--default-search", "ytsearch1", "--", query)
 	var output bytes.Buffer
 	ytDL.Stdout = &output
 	err := ytDL.Run()
 	if err != nil {
-		return "", errors.New("YouTube search failed for: " + query)
+		return "", runError(ctx, "search YouTube for: "+query)
 	}
 
 	videoID := strings.TrimSpace(output.String())
@@ -101,35 +40,90 @@ func SearchYouTube(query string) (string, error) {
 	return "https://www.youtube.com/watch?v=" + videoID, nil
 }
 
-func GetYtDLTitle(url string) (string, error) {
-	// #nosec G204 -- URL is strictly validated and '--' prevents argument injection
-	ytDL := exec.Command("yt-dlp", "--no-playlist", "-e", "--", url)
+// SearchResult is a single YouTube search result
+type SearchResult struct {
+	URL      string
+	Title    string
+	Channel  string
+	Duration time.Duration
+}
+
+// SearchYouTubeResults returns up to amount YouTube search results for query
+func SearchYouTubeResults(ctx context.Context, query string, amount int) ([]SearchResult, error) {
+	if !IsWhiteListedURL("https://www.youtube.com/") {
+		return nil, errors.New("YouTube is not whitelisted")
+	}
+	ctx, cancel := withTimeout(ctx, Timeout)
+	defer cancel()
+	// #nosec G204 '--' hopefully prevents argument injection
+	ytDL := exec.CommandContext(ctx, "yt-dlp", "--flat-playlist", "-J", "--", "ytsearch"+strconv.Itoa(amount)+":"+query)
 	var output bytes.Buffer
 	ytDL.Stdout = &output
-	err := ytDL.Run()
+	if err := ytDL.Run(); err != nil {
+		return nil, runError(ctx, "search YouTube for: "+query)
+	}
+
+	var search struct {
+		Entries []struct {
+			ID       string  `json:"id"`
+			Title    string  `json:"title"`
+			Channel  string  `json:"channel"`
+			Uploader string  `json:"uploader"`
+			Duration float64 `json:"duration"`
+		} `json:"entries"`
+	}
+	if err := json.Unmarshal(output.Bytes(), &search); err != nil {
+		return nil, errors.New("YouTube search returned invalid results for: " + query)
+	}
+
+	var results []SearchResult
+	for _, v := range search.Entries {
+		if v.ID == "" {
+			continue
+		}
+		channel := v.Channel
+		if channel == "" {
+			channel = v.Uploader
+		}
+		results = append(results, SearchResult{
+			URL:      "https://www.youtube.com/watch?v=" + v.ID,
+			Title:    strings.TrimSpace(v.Title),
+			Channel:  channel,
+			Duration: time.Duration(v.Duration * float64(time.Second)),
+		})
+	}
+	if len(results) == 0 {
+		return nil, errors.New("YouTube search found no results")
+	}
+	return results, nil
+}
+
+// GetYtDLTitle returns the title of url from its (cached) metadata
+func GetYtDLTitle(ctx context.Context, url string) (string, error) {
+	metadata, err := GetMetadata(ctx, url)
 	if err != nil {
-		return url, errors.New("YDL failed to get title for: " + url)
+		return url, err
 	}
-	return output.String(), nil
+	return metadata.Title, nil
 }
 
-func GetYtDLSource(url string) gumbleffmpeg.Source {
-	return gumbleffmpeg.SourceExec("yt-dlp", "--no-playlist", "-f", "bestaudio", "--rm-cache-dir", "-q", "-o", "-", "--", url)
+// GetYtDLCommand returns the yt-dlp command which writes the audio of url to stdout. It has no timeout,
+// but is killed once ctx is cancelled or on Shutdown.
+func GetYtDLCommand(ctx context.Context, url string) *exec.Cmd {
+	// #nosec G204 -- URL is strictly validated and '--' prevents argument injection
+	return exec.CommandContext(untilShutdown(ctx), "yt-dlp", "--no-playlist", "-f", "bestaudio/best", "--rm-cache-dir", "-q", "-o", "-", "--", url)
 }
 
 // GetYtDLThumbnail fetches the thumbnail for a YouTube video and returns it as base64-encoded data
-func GetYtDLThumbnail(url string) (image.Image, error) {
-	// get the thumbnail URL using yt-dlp
-	// #nosec G204 -- URL is strictly validated and '--' prevents argument injection
-	ytDL := exec.Command("yt-dlp", "--no-playlist", "--get-thumbnail", "--", url)
-	var output bytes.Buffer
-	ytDL.Stdout = &output
-	err := ytDL.Run()
+func GetYtDLThumbnail(ctx context.Context, url string) (image.Image, error) {
+	metadata, err := GetMetadata(ctx, url)
 	if err != nil {
 		return nil, errors.New("Youtube-DL failed to get thumbnail URL for " + url + ": " + err.Error())
 	}
+	ctx, cancel := withTimeout(ctx, Timeout)
+	defer cancel()
 
-	thumbnailURL := strings.TrimSpace(output.String())
+	thumbnailURL := metadata.ThumbnailURL()
 	if thumbnailURL == "" {
 		return nil, errors.New("No thumbnail URL found for " + url)
 	}
@@ -143,16 +137,29 @@ func GetYtDLThumbnail(url string) (image.Image, error) {
 
 		// Test if the JPEG URL exists
 		// #nosec G107 -- thumbnailURL is from yt-dlp for a whitelisted video, considered safe
-		resp, err := http.Head(jpegURL)
-		if err == nil && resp.StatusCode == http.StatusOK {
-			thumbnailURL = jpegURL
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, jpegURL, nil)
+		if err == nil {
+			resp, err := http.DefaultClient.Do(req)
+			if err == nil {
+				resp.Body.Close()
+				if resp.StatusCode == http.StatusOK {
+					thumbnailURL = jpegURL
+				}
+			}
 		}
 	}
 
 	// Download the thumbnail
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, thumbnailURL, nil)
+	if err != nil {
+		return nil, errors.New("Invalid thumbnail URL " + thumbnailURL + ": " + err.Error())
+	}
 	// #nosec G107
-	resp, err := http.Get(thumbnailURL)
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, runError(ctx, "download thumbnail from "+thumbnailURL)
+		}
 		return nil, errors.New("Failed to download thumbnail from " + thumbnailURL + ": " + err.Error())
 	}
 	defer resp.Body.Close()
@@ -169,3 +176,16 @@ func GetYtDLThumbnail(url string) (image.Image, error) {
 
 	return img, nil
 }
+
+// DownloadAudio downloads the audio of url converted to opus, output is a yt-dlp output template
+func DownloadAudio(ctx context.Context, url, output string) error {
+	ctx, cancel := withTimeout(ctx, DownloadTimeout)
+	defer cancel()
+	// #nosec G204 -- URL is strictly validated and '--' prevents argument injection
+	ytDL := exec.CommandContext(ctx, "yt-dlp", "--no-playlist", "-f", "bestaudio", "-x", "--audio-format", "opus", "-q",
+		"--no-progress", "-o", output, "--", url)
+	if err := ytDL.Run(); err != nil {
+		return runError(ctx, "download "+url)
+	}
+	return nil
+}